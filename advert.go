@@ -0,0 +1,266 @@
+package meshcore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/kellegous/poop"
+)
+
+// AdvertFlags describes which of the optional fields are present in the app
+// data of an advert.
+type AdvertFlags byte
+
+const (
+	AdvertFlagLocation AdvertFlags = 0x10
+	AdvertFlagFeature1 AdvertFlags = 0x20
+	AdvertFlagFeature2 AdvertFlags = 0x40
+	AdvertFlagName     AdvertFlags = 0x80
+)
+
+func (f AdvertFlags) Has(flag AdvertFlags) bool {
+	return f&flag == flag
+}
+
+const (
+	// maxAdvertDataSize is the largest app data the firmware will accept.
+	maxAdvertDataSize = 32
+
+	advertTypeMask = 0x0f
+
+	// advertPacketHeader is a flood routed advert (payload type 4), which
+	// is how the firmware exports adverts.
+	advertPacketHeader = 0x11
+
+	routeTypeMask            = 0x03
+	routeTypeTransportFlood  = 0x00
+	routeTypeTransportDirect = 0x03
+	payloadTypeShift         = 2
+	payloadTypeMask          = 0x0f
+	payloadTypeAdvert        = 0x04
+)
+
+// AdvertData is the application data carried by an advert.
+type AdvertData struct {
+	Type ContactType
+	// Flags reports which of the optional fields below are present.
+	Flags    AdvertFlags
+	Lat      float64
+	Lon      float64
+	Feature1 uint16
+	Feature2 uint16
+	Name     string
+}
+
+func (a *AdvertData) readFrom(r io.Reader) error {
+	var flags byte
+	if err := binary.Read(r, binary.LittleEndian, &flags); err != nil {
+		return poop.Chain(err)
+	}
+	a.Type = ContactType(flags & advertTypeMask)
+	a.Flags = AdvertFlags(flags &^ advertTypeMask)
+
+	if a.Flags.Has(AdvertFlagLocation) {
+		var lat, lon int32
+		if err := binary.Read(r, binary.LittleEndian, &lat); err != nil {
+			return poop.Chain(err)
+		}
+		if err := binary.Read(r, binary.LittleEndian, &lon); err != nil {
+			return poop.Chain(err)
+		}
+		a.Lat, a.Lon = float64(lat)/1e6, float64(lon)/1e6
+	}
+
+	if a.Flags.Has(AdvertFlagFeature1) {
+		if err := binary.Read(r, binary.LittleEndian, &a.Feature1); err != nil {
+			return poop.Chain(err)
+		}
+	}
+
+	if a.Flags.Has(AdvertFlagFeature2) {
+		if err := binary.Read(r, binary.LittleEndian, &a.Feature2); err != nil {
+			return poop.Chain(err)
+		}
+	}
+
+	if a.Flags.Has(AdvertFlagName) {
+		var err error
+		a.Name, err = readString(r)
+		if err != nil {
+			return poop.Chain(err)
+		}
+	}
+
+	return nil
+}
+
+func (a *AdvertData) writeTo(w io.Writer) error {
+	if a.Type > advertTypeMask {
+		return poop.Newf("invalid advert type: %d", a.Type)
+	}
+
+	flags := byte(a.Type) | byte(a.Flags&^advertTypeMask)
+	if err := binary.Write(w, binary.LittleEndian, flags); err != nil {
+		return poop.Chain(err)
+	}
+
+	if a.Flags.Has(AdvertFlagLocation) {
+		if err := binary.Write(w, binary.LittleEndian, int32(a.Lat*1e6)); err != nil {
+			return poop.Chain(err)
+		}
+		if err := binary.Write(w, binary.LittleEndian, int32(a.Lon*1e6)); err != nil {
+			return poop.Chain(err)
+		}
+	}
+
+	if a.Flags.Has(AdvertFlagFeature1) {
+		if err := binary.Write(w, binary.LittleEndian, a.Feature1); err != nil {
+			return poop.Chain(err)
+		}
+	}
+
+	if a.Flags.Has(AdvertFlagFeature2) {
+		if err := binary.Write(w, binary.LittleEndian, a.Feature2); err != nil {
+			return poop.Chain(err)
+		}
+	}
+
+	if a.Flags.Has(AdvertFlagName) {
+		if err := writeString(w, a.Name); err != nil {
+			return poop.Chain(err)
+		}
+	}
+
+	return nil
+}
+
+// Advert is a signed advertisement of a node's identity, as carried in an
+// advert packet and in the blobs used by Conn.ExportContact and
+// Conn.ImportContact.
+type Advert struct {
+	PublicKey PublicKey
+	Timestamp time.Time
+	Signature [64]byte
+	Data      AdvertData
+
+	// appData is the app data exactly as it was signed.
+	appData []byte
+}
+
+// NewAdvert creates an advert for the given data signed with key.
+func NewAdvert(key *PrivateKey, timestamp time.Time, data *AdvertData) (*Advert, error) {
+	var buf bytes.Buffer
+	if err := data.writeTo(&buf); err != nil {
+		return nil, poop.Chain(err)
+	}
+	if buf.Len() > maxAdvertDataSize {
+		return nil, poop.Newf("advert data is %d bytes, max is %d", buf.Len(), maxAdvertDataSize)
+	}
+
+	a := &Advert{
+		PublicKey: key.PublicKey(),
+		Timestamp: timestamp,
+		Data:      *data,
+		appData:   buf.Bytes(),
+	}
+
+	copy(a.Signature[:], key.Sign(a.signedData()))
+
+	return a, nil
+}
+
+// ParseAdvert parses the payload of an advert packet.
+func ParseAdvert(payload []byte) (*Advert, error) {
+	var a Advert
+	r := bytes.NewReader(payload)
+
+	if err := a.PublicKey.readFrom(r); err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	var err error
+	a.Timestamp, err = readTime(r)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	if _, err := io.ReadFull(r, a.Signature[:]); err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	a.appData, err = io.ReadAll(r)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	if len(a.appData) > 0 {
+		if err := a.Data.readFrom(bytes.NewReader(a.appData)); err != nil {
+			return nil, poop.Chain(err)
+		}
+	}
+
+	return &a, nil
+}
+
+// ParseAdvertPacket parses a complete advert packet, such as the one returned
+// by Conn.ExportContact.
+func ParseAdvertPacket(packet []byte) (*Advert, error) {
+	if len(packet) < 2 {
+		return nil, poop.New("packet is too short")
+	}
+
+	header := packet[0]
+	if (header>>payloadTypeShift)&payloadTypeMask != payloadTypeAdvert {
+		return nil, poop.Newf("packet is not an advert (header %#02x)", header)
+	}
+
+	rest := packet[1:]
+	switch header & routeTypeMask {
+	case routeTypeTransportFlood, routeTypeTransportDirect:
+		if len(rest) < 4 {
+			return nil, poop.New("packet is too short")
+		}
+		rest = rest[4:]
+	}
+
+	if len(rest) < 1 || len(rest) < 1+int(rest[0]) {
+		return nil, poop.New("packet is too short")
+	}
+	rest = rest[1+int(rest[0]):]
+
+	return ParseAdvert(rest)
+}
+
+func (a *Advert) signedData() []byte {
+	var buf bytes.Buffer
+	buf.Write(a.PublicKey.Bytes())
+	binary.Write(&buf, binary.LittleEndian, uint32(a.Timestamp.Unix()))
+	buf.Write(a.appData)
+	return buf.Bytes()
+}
+
+// Verify reports whether the advert's signature is valid for its public key.
+func (a *Advert) Verify() bool {
+	return a.PublicKey.Verify(a.signedData(), a.Signature[:])
+}
+
+// Payload returns the encoded advert payload.
+func (a *Advert) Payload() []byte {
+	var buf bytes.Buffer
+	buf.Write(a.PublicKey.Bytes())
+	binary.Write(&buf, binary.LittleEndian, uint32(a.Timestamp.Unix()))
+	buf.Write(a.Signature[:])
+	buf.Write(a.appData)
+	return buf.Bytes()
+}
+
+// Packet returns the advert encoded as a flood packet, the format accepted
+// by Conn.ImportContact.
+func (a *Advert) Packet() []byte {
+	payload := a.Payload()
+	packet := make([]byte, 0, len(payload)+2)
+	packet = append(packet, advertPacketHeader, 0)
+	return append(packet, payload...)
+}
@@ -0,0 +1,149 @@
+package meshcore
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdvert(t *testing.T) {
+	key, _ := fakePrivateKey(t, 7)
+
+	tests := []struct {
+		Name string
+		Data AdvertData
+	}{
+		{
+			Name: "chat with name",
+			Data: AdvertData{
+				Type:  ContactTypeChat,
+				Flags: AdvertFlagName,
+				Name:  "kellegous",
+			},
+		},
+		{
+			Name: "repeater with location",
+			Data: AdvertData{
+				Type:  ContactTypeRepeater,
+				Flags: AdvertFlagLocation | AdvertFlagName,
+				Lat:   37.774929,
+				Lon:   -122.419416,
+				Name:  "hill top",
+			},
+		},
+		{
+			Name: "sensor with features",
+			Data: AdvertData{
+				Type:     ContactTypeSensor,
+				Flags:    AdvertFlagFeature1 | AdvertFlagFeature2,
+				Feature1: 0x1234,
+				Feature2: 0x5678,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			advert, err := NewAdvert(&key, time.Unix(1700000000, 0), &test.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := ParseAdvertPacket(advert.Packet())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(parsed, advert) {
+				t.Fatalf("expected %s, got %s", describe(advert), describe(parsed))
+			}
+
+			if !parsed.Verify() {
+				t.Fatal("expected advert to verify")
+			}
+
+			parsed.Timestamp = parsed.Timestamp.Add(time.Second)
+			if parsed.Verify() {
+				t.Fatal("expected tampered advert to fail verification")
+			}
+		})
+	}
+}
+
+func TestParseAdvertPacket(t *testing.T) {
+	key, _ := fakePrivateKey(t, 7)
+	advert, err := NewAdvert(&key, time.Unix(1700000000, 0), &AdvertData{
+		Type:  ContactTypeRoom,
+		Flags: AdvertFlagName,
+		Name:  "room",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := advert.Payload()
+
+	tests := []struct {
+		Name          string
+		Packet        []byte
+		ExpectedError string
+	}{
+		{
+			Name:   "with path",
+			Packet: append([]byte{0x11, 2, 0xaa, 0xbb}, payload...),
+		},
+		{
+			Name:   "with transport codes",
+			Packet: append([]byte{0x10, 1, 2, 3, 4, 0}, payload...),
+		},
+		{
+			Name:          "not an advert",
+			Packet:        append([]byte{0x09, 0}, payload...),
+			ExpectedError: "packet is not an advert (header 0x09)",
+		},
+		{
+			Name:          "truncated path",
+			Packet:        []byte{0x11, 4, 0xaa},
+			ExpectedError: "packet is too short",
+		},
+		{
+			Name:          "truncated payload",
+			Packet:        append([]byte{0x11, 0}, payload[:40]...),
+			ExpectedError: "unexpected EOF",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			parsed, err := ParseAdvertPacket(test.Packet)
+			if test.ExpectedError != "" {
+				if err == nil || err.Error() != test.ExpectedError {
+					t.Fatalf("expected error %q, got %v", test.ExpectedError, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if !parsed.Verify() {
+				t.Fatal("expected advert to verify")
+			}
+			if parsed.Data.Name != "room" || parsed.Data.Type != ContactTypeRoom {
+				t.Fatalf("unexpected advert data: %s", describe(parsed.Data))
+			}
+		})
+	}
+}
+
+func TestNewAdvertTooLarge(t *testing.T) {
+	key, _ := fakePrivateKey(t, 7)
+	_, err := NewAdvert(&key, time.Unix(1700000000, 0), &AdvertData{
+		Type:  ContactTypeChat,
+		Flags: AdvertFlagLocation | AdvertFlagName,
+		Name:  strings.Repeat("x", 30),
+	})
+	if err == nil || err.Error() != "advert data is 39 bytes, max is 32" {
+		t.Fatalf("expected error: advert data is 39 bytes, max is 32, got %v", err)
+	}
+}
@@ -0,0 +1,309 @@
+package meshcore
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kellegous/poop"
+)
+
+// DefaultPreambleLength is the preamble length, in symbols, used by MeshCore
+// radios.
+const DefaultPreambleLength = 16
+
+// AirtimeOptions are the LoRa modem settings that affect time on air but are
+// not part of RadioParams. The zero value matches MeshCore radios.
+type AirtimeOptions struct {
+	// PreambleLength is the preamble length in symbols. If zero,
+	// DefaultPreambleLength is used.
+	PreambleLength int
+	// ImplicitHeader omits the LoRa header from packets.
+	ImplicitHeader bool
+	// DisableCRC omits the payload CRC from packets.
+	DisableCRC bool
+}
+
+// Airtime returns the time on air of a packet with a payload of size bytes,
+// using the formula from Semtech's LoRa modem designer's guide. Low data rate
+// optimization is enabled when the symbol time exceeds 16ms, as the radio
+// drivers do.
+func (r *RadioParams) Airtime(size int, opts *AirtimeOptions) time.Duration {
+	if opts == nil {
+		opts = &AirtimeOptions{}
+	}
+
+	preamble := opts.PreambleLength
+	if preamble == 0 {
+		preamble = DefaultPreambleLength
+	}
+
+	sf := float64(r.Sf)
+	symbol := math.Exp2(sf) / (r.Bw * 1000)
+
+	var de, h, crc float64
+	if symbol > 0.016 {
+		de = 1
+	}
+	if opts.ImplicitHeader {
+		h = 1
+	}
+	if !opts.DisableCRC {
+		crc = 1
+	}
+
+	n := math.Ceil((8*float64(size)-4*sf+28+16*crc-20*h)/(4*(sf-2*de))) * float64(r.Cr)
+	symbols := float64(preamble) + 4.25 + 8 + math.Max(n, 0)
+
+	return time.Duration(symbols * symbol * float64(time.Second))
+}
+
+// Estimated sizes, in bytes, of the packets the device sends for each
+// command. Senders' names and routes are not known to Conn, so channel
+// messages assume the longest name and direct packets assume no path.
+const (
+	// packetOverhead is the header and path length.
+	packetOverhead = 2
+	maxNameSize    = 31
+	// advertSize is a public key, timestamp, signature, flags, location and
+	// name.
+	advertSize = 32 + 4 + 64 + 1 + 8 + maxNameSize
+)
+
+// encryptedSize returns the size of n bytes of plaintext once encrypted.
+func encryptedSize(n int) int {
+	return 2 + (n+15)/16*16
+}
+
+func textMessagePacketSize(message string) int {
+	return packetOverhead + 2 + encryptedSize(4+1+len(message))
+}
+
+func channelTextMessagePacketSize(message string) int {
+	return packetOverhead + 1 + encryptedSize(4+1+maxNameSize+2+len(message))
+}
+
+func advertPacketSize() int {
+	return packetOverhead + advertSize
+}
+
+func rawDataPacketSize(path, payload []byte) int {
+	return packetOverhead + len(path) + len(payload)
+}
+
+func binaryRequestPacketSize(payload []byte) int {
+	return packetOverhead + 2 + encryptedSize(4+len(payload))
+}
+
+// requestPacketSize is the size of a status or telemetry request, which is a
+// request type and a few bytes of options.
+func requestPacketSize() int {
+	return binaryRequestPacketSize(make([]byte, 1+4))
+}
+
+// loginPacketSize is the size of a login, which carries the sender's public
+// key since the server may not know it.
+func loginPacketSize(password string) int {
+	return packetOverhead + 1 + 32 + encryptedSize(4+len(password))
+}
+
+// tracePacketSize is the size of a trace as it is first sent. It is a tag,
+// an auth code, flags and the hashes of the hops.
+func tracePacketSize(path []byte) int {
+	return packetOverhead + 4 + 4 + 1 + len(path)
+}
+
+// ErrDutyCycleExceeded is returned by sends that would exceed the budget of a
+// DutyCycle that blocks.
+var ErrDutyCycleExceeded = errors.New("duty cycle budget exceeded")
+
+// DutyCycleOptions configures a DutyCycle.
+type DutyCycleOptions struct {
+	// Window is the length of the sliding window. If zero, one hour is used.
+	Window time.Duration
+	// Budget is the airtime allowed in each window. If zero, there is no
+	// budget and airtime is only tracked.
+	Budget time.Duration
+	// Block makes sends that would exceed the budget fail with
+	// ErrDutyCycleExceeded instead of being sent.
+	Block bool
+	// Airtime configures how airtime is computed.
+	Airtime *AirtimeOptions
+}
+
+// DutyCycle tracks the airtime used by the packets sent through a Conn over a
+// sliding window. Use Conn.SetDutyCycle to attach it to a connection.
+type DutyCycle struct {
+	opts DutyCycleOptions
+	now  func() time.Time
+
+	mu    sync.Mutex
+	radio RadioParams
+	sends []*dutyCycleSend
+}
+
+type dutyCycleSend struct {
+	at      time.Time
+	airtime time.Duration
+}
+
+// NewDutyCycle creates a DutyCycle for a radio using the given parameters.
+// For example, a 10% duty cycle in the EU's 869.4-869.65 MHz band is:
+//
+//	NewDutyCycle(params, &DutyCycleOptions{
+//		Window: time.Hour,
+//		Budget: 6 * time.Minute,
+//		Block:  true,
+//	})
+func NewDutyCycle(radio RadioParams, opts *DutyCycleOptions) *DutyCycle {
+	d := &DutyCycle{
+		now:   time.Now,
+		radio: radio,
+	}
+	if opts != nil {
+		d.opts = *opts
+	}
+	if d.opts.Window == 0 {
+		d.opts.Window = time.Hour
+	}
+	return d
+}
+
+// SetRadio changes the radio parameters used to compute airtime. Conn calls
+// it when the radio parameters are changed with Conn.SetRadioParams.
+func (d *DutyCycle) SetRadio(radio RadioParams) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.radio = radio
+}
+
+// Window returns the length of the sliding window.
+func (d *DutyCycle) Window() time.Duration {
+	return d.opts.Window
+}
+
+// Used returns the airtime used in the current window.
+func (d *DutyCycle) Used() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.used(d.now())
+}
+
+// Utilization returns the fraction of the current window that was spent
+// transmitting.
+func (d *DutyCycle) Utilization() float64 {
+	return float64(d.Used()) / float64(d.opts.Window)
+}
+
+// Remaining returns the airtime left in the budget for the current window.
+// It returns zero if there is no budget.
+func (d *DutyCycle) Remaining() time.Duration {
+	if d.opts.Budget == 0 {
+		return 0
+	}
+	return max(d.opts.Budget-d.Used(), 0)
+}
+
+// Record records a packet of size bytes that was sent now and returns its
+// airtime. Sends made through Conn are recorded automatically.
+func (d *DutyCycle) Record(size int) time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	airtime := d.radio.Airtime(size, d.opts.Airtime)
+	now := d.now()
+	d.used(now)
+	d.sends = append(d.sends, &dutyCycleSend{at: now, airtime: airtime})
+	return airtime
+}
+
+// reserve records a packet of size bytes that is about to be sent. If it
+// would exceed a blocking budget, it is not recorded and an error is
+// returned.
+func (d *DutyCycle) reserve(size int) (*airtimeReservation, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	airtime := d.radio.Airtime(size, d.opts.Airtime)
+	now := d.now()
+	used := d.used(now)
+	if d.opts.Block && d.opts.Budget > 0 && used+airtime > d.opts.Budget {
+		return nil, poop.ChainWithf(
+			ErrDutyCycleExceeded,
+			"duty cycle budget exceeded: %s used of %s, send needs %s",
+			used.Round(time.Millisecond),
+			d.opts.Budget,
+			airtime.Round(time.Millisecond))
+	}
+
+	send := &dutyCycleSend{at: now, airtime: airtime}
+	d.sends = append(d.sends, send)
+	return &airtimeReservation{d: d, send: send}, nil
+}
+
+// refund removes a send that was reserved but never made.
+func (d *DutyCycle) refund(send *dutyCycleSend) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if i := slices.Index(d.sends, send); i >= 0 {
+		d.sends = slices.Delete(d.sends, i, i+1)
+	}
+}
+
+// airtimeReservation is the airtime charged for a send before it is made.
+// Unless keep is called once the command is written, release refunds
+// it. A nil reservation does nothing.
+type airtimeReservation struct {
+	d    *DutyCycle
+	send *dutyCycleSend
+	kept bool
+}
+
+func (r *airtimeReservation) keep() {
+	if r != nil {
+		r.kept = true
+	}
+}
+
+func (r *airtimeReservation) release() {
+	if r != nil && !r.kept {
+		r.d.refund(r.send)
+	}
+}
+
+// used drops sends that are outside of the window ending at now and returns
+// the airtime of the rest.
+func (d *DutyCycle) used(now time.Time) time.Duration {
+	start := now.Add(-d.opts.Window)
+	i := 0
+	for i < len(d.sends) && !d.sends[i].at.After(start) {
+		i++
+	}
+	d.sends = d.sends[i:]
+
+	var used time.Duration
+	for _, send := range d.sends {
+		used += send.airtime
+	}
+	return used
+}
+
+// SetDutyCycle attaches a DutyCycle that records the packets sent by text
+// messages, channel messages, adverts, shared contacts, raw data, binary,
+// status and telemetry requests, logins and traces. Pass nil to stop
+// tracking. It should be called before the connection is used.
+func (c *Conn) SetDutyCycle(d *DutyCycle) {
+	c.dutyCycle = d
+}
+
+// reserveAirtime records a send of size bytes with the attached DutyCycle,
+// if there is one. The caller must release the reservation, keeping it once
+// the command has been written to the device.
+func (c *Conn) reserveAirtime(size int) (*airtimeReservation, error) {
+	if c.dutyCycle == nil {
+		return nil, nil
+	}
+	return c.dutyCycle.reserve(size)
+}
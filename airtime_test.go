@@ -0,0 +1,194 @@
+package meshcore
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAirtime(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Params   RadioParams
+		Size     int
+		Opts     *AirtimeOptions
+		Expected time.Duration
+	}{
+		{
+			Name:     "sf7",
+			Params:   RadioParams{Freq: 868.1, Bw: 125, Sf: 7, Cr: 5},
+			Size:     20,
+			Opts:     &AirtimeOptions{PreambleLength: 8},
+			Expected: 56576 * time.Microsecond,
+		},
+		{
+			Name:     "sf12 low data rate optimization",
+			Params:   RadioParams{Freq: 868.1, Bw: 125, Sf: 12, Cr: 5},
+			Size:     10,
+			Opts:     &AirtimeOptions{PreambleLength: 8},
+			Expected: 991232 * time.Microsecond,
+		},
+		{
+			Name:     "implicit header without crc",
+			Params:   RadioParams{Freq: 868.1, Bw: 125, Sf: 7, Cr: 5},
+			Size:     20,
+			Opts:     &AirtimeOptions{PreambleLength: 8, ImplicitHeader: true, DisableCRC: true},
+			Expected: 46336 * time.Microsecond,
+		},
+		{
+			Name:     "meshcore defaults",
+			Params:   RadioParams{Freq: 910.525, Bw: 62.5, Sf: 7, Cr: 5},
+			Size:     20,
+			Expected: 129536 * time.Microsecond,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			airtime := test.Params.Airtime(test.Size, test.Opts)
+			if airtime.Round(time.Microsecond) != test.Expected {
+				t.Fatalf("expected %s, got %s", test.Expected, airtime)
+			}
+		})
+	}
+}
+
+func TestDutyCycle(t *testing.T) {
+	params := RadioParams{Freq: 869.525, Bw: 250, Sf: 11, Cr: 5}
+	now := time.Unix(1000, 0)
+	d := NewDutyCycle(params, &DutyCycleOptions{Window: time.Minute})
+	d.now = func() time.Time { return now }
+
+	first := d.Record(100)
+	if first != params.Airtime(100, nil) {
+		t.Fatalf("expected %s, got %s", params.Airtime(100, nil), first)
+	}
+
+	now = now.Add(30 * time.Second)
+	second := d.Record(50)
+	if used := d.Used(); used != first+second {
+		t.Fatalf("expected %s, got %s", first+second, used)
+	}
+
+	now = now.Add(31 * time.Second)
+	if used := d.Used(); used != second {
+		t.Fatalf("expected %s once the first send left the window, got %s", second, used)
+	}
+	if u := d.Utilization(); u != float64(second)/float64(time.Minute) {
+		t.Fatalf("unexpected utilization: %f", u)
+	}
+}
+
+func TestConnDutyCycle(t *testing.T) {
+	params := RadioParams{Freq: 869.525, Bw: 250, Sf: 11, Cr: 5}
+	message := "hello"
+
+	t.Run("track", func(t *testing.T) {
+		d := NewDutyCycle(params, nil)
+		controller := DoCommand(func(conn *Conn) {
+			conn.SetDutyCycle(d)
+			if err := conn.SendChannelTextMessage(t.Context(), 0, message, TextTypePlain); err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		controller.Recv()
+		controller.Notify(NotificationTypeOk, nil)
+		controller.Wait()
+
+		expected := params.Airtime(channelTextMessagePacketSize(message), nil)
+		if used := d.Used(); used != expected {
+			t.Fatalf("expected %s, got %s", expected, used)
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		advert := params.Airtime(advertPacketSize(), nil)
+		d := NewDutyCycle(params, &DutyCycleOptions{
+			Budget: advert + advert/2,
+			Block:  true,
+		})
+		controller := DoCommand(func(conn *Conn) {
+			conn.SetDutyCycle(d)
+			if err := conn.SendAdvert(t.Context(), SelfAdvertTypeFlood); err != nil {
+				t.Fatal(err)
+			}
+			if err := conn.SendAdvert(t.Context(), SelfAdvertTypeFlood); !errors.Is(err, ErrDutyCycleExceeded) {
+				t.Fatalf("expected ErrDutyCycleExceeded, got %v", err)
+			}
+		})
+
+		controller.Recv()
+		controller.Notify(NotificationTypeOk, nil)
+		controller.Wait()
+
+		if used := d.Used(); used != advert {
+			t.Fatalf("expected only the first advert to be recorded, got %s", used)
+		}
+		if remaining := d.Remaining(); remaining != advert/2 {
+			t.Fatalf("expected %s remaining, got %s", advert/2, remaining)
+		}
+	})
+	t.Run("refund failed writes", func(t *testing.T) {
+		d := NewDutyCycle(params, nil)
+		controller := DoCommand(func(conn *Conn) {
+			conn.SetDutyCycle(d)
+
+			fail := true
+			conn.Use(Middleware{
+				Send: func(next SendFunc) SendFunc {
+					return func(frame []byte) error {
+						if fail {
+							fail = false
+							return errors.New("write failed")
+						}
+						return next(frame)
+					}
+				},
+			})
+
+			if err := conn.SendAdvert(t.Context(), SelfAdvertTypeFlood); err == nil {
+				t.Fatal("expected the write to fail")
+			}
+			if used := d.Used(); used != 0 {
+				t.Fatalf("expected the failed advert to be refunded, got %s", used)
+			}
+			if _, err := conn.SendTextMessage(t.Context(), &PublicKey{}, message, TextTypePlain); err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		controller.Recv()
+		controller.Notify(NotificationTypeSent, BytesFrom(
+			Byte(0),
+			Uint32(1, binary.LittleEndian),
+			Uint32(1000, binary.LittleEndian),
+		))
+		controller.Wait()
+
+		expected := params.Airtime(textMessagePacketSize(message), nil)
+		if used := d.Used(); used != expected {
+			t.Fatalf("expected %s, got %s", expected, used)
+		}
+	})
+
+	t.Run("keep written sends", func(t *testing.T) {
+		d := NewDutyCycle(params, nil)
+		controller := DoCommand(func(conn *Conn) {
+			conn.SetDutyCycle(d)
+			if err := conn.ShareContact(t.Context(), fakePublicKey(7)); err == nil {
+				t.Fatal("expected the device to reject the share")
+			}
+		})
+
+		controller.Recv()
+		controller.Notify(NotificationTypeErr, BytesFrom(Byte(byte(ErrorCodeFileIOError))))
+		controller.Wait()
+
+		expected := params.Airtime(advertPacketSize(), nil)
+		if used := d.Used(); used != expected {
+			t.Fatalf("expected %s, got %s", expected, used)
+		}
+	})
+}
@@ -0,0 +1,337 @@
+package meshcore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/kellegous/poop"
+)
+
+// ArchiveVersion is the version of the archive format written by Backup.
+const ArchiveVersion = 1
+
+// Archive is a backup of a device's configuration created by Backup and
+// applied to a device with Restore.
+type Archive struct {
+	Version   int
+	CreatedAt time.Time
+	SelfInfo  *SelfInfo
+	// TuningParams is nil if the device does not support tuning parameters.
+	TuningParams *TuningParams `json:",omitempty"`
+	Contacts     []*ArchivedContact
+	// Channels holds the channels that are in use. Empty slots are not
+	// archived.
+	Channels []*ChannelInfo
+	// PrivateKey is the device's private key when the archive was created
+	// without a passphrase.
+	PrivateKey []byte `json:",omitempty"`
+	// SealedPrivateKey is the device's private key sealed with
+	// SealPrivateKey when the archive was created with a passphrase.
+	SealedPrivateKey string `json:",omitempty"`
+}
+
+// ArchivedContact is a contact along with the advert exported for it by the
+// device, which is nil if the device could not export one.
+type ArchivedContact struct {
+	Contact *Contact
+	Advert  []byte `json:",omitempty"`
+}
+
+// BackupOptions configures Backup.
+type BackupOptions struct {
+	// Passphrase is used to seal the private key. If empty, the private key
+	// is stored in the clear.
+	Passphrase string
+	// SkipPrivateKey excludes the private key from the archive.
+	SkipPrivateKey bool
+}
+
+// Backup captures the device's configuration into an archive. If the device
+// does not allow its private key to be exported, the archive is created
+// without it.
+func Backup(ctx context.Context, conn *Conn, opts *BackupOptions) (*Archive, error) {
+	if opts == nil {
+		opts = &BackupOptions{}
+	}
+
+	info, err := conn.GetSelfInfo(ctx)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	a := &Archive{
+		Version:   ArchiveVersion,
+		CreatedAt: time.Now(),
+		SelfInfo:  info,
+	}
+
+	a.TuningParams, err = conn.GetTuningParams(ctx)
+	if hasErrorCode(err, ErrorCodeUnsupportedCommand) {
+		a.TuningParams = nil
+	} else if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	contacts, err := conn.GetContacts(ctx, nil)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	for _, contact := range contacts {
+		advert, err := conn.ExportContact(ctx, &contact.PublicKey)
+		var cerr *CommandError
+		if errors.As(err, &cerr) {
+			advert = nil
+		} else if err != nil {
+			return nil, poop.Chain(err)
+		}
+
+		a.Contacts = append(a.Contacts, &ArchivedContact{
+			Contact: contact,
+			Advert:  advert,
+		})
+	}
+
+	channels, err := conn.GetChannels(ctx)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	for _, channel := range channels {
+		if !channel.IsEmpty() {
+			a.Channels = append(a.Channels, channel)
+		}
+	}
+
+	if opts.SkipPrivateKey {
+		return a, nil
+	}
+
+	b, err := conn.ExportPrivateKey(ctx)
+	if err != nil {
+		var cerr *CommandError
+		if errors.As(err, &cerr) || errors.Is(err, ErrPrivateKeyDisabled) {
+			return a, nil
+		}
+		return nil, poop.Chain(err)
+	}
+
+	if opts.Passphrase == "" {
+		a.PrivateKey = b
+		return a, nil
+	}
+
+	key, err := PrivateKeyFromBytes(b)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	sealed, err := SealPrivateKey(&key, opts.Passphrase)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	a.SealedPrivateKey = string(sealed)
+
+	return a, nil
+}
+
+// ReadArchive reads an archive written with Archive.Write.
+func ReadArchive(r io.Reader) (*Archive, error) {
+	var a Archive
+	if err := json.NewDecoder(r).Decode(&a); err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	if a.Version != ArchiveVersion {
+		return nil, poop.Newf("unsupported archive version: %d", a.Version)
+	}
+
+	if a.SelfInfo == nil {
+		return nil, poop.New("archive has no self info")
+	}
+
+	return &a, nil
+}
+
+// Write writes the archive as JSON.
+func (a *Archive) Write(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	if err := e.Encode(a); err != nil {
+		return poop.Chain(err)
+	}
+	return nil
+}
+
+// privateKey returns the archived private key, opening it with passphrase
+// if it is sealed. It returns nil if the archive has no private key.
+func (a *Archive) privateKey(passphrase string) (*PrivateKey, error) {
+	switch {
+	case a.SealedPrivateKey != "":
+		key, err := OpenPrivateKey([]byte(a.SealedPrivateKey), passphrase)
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+		return &key, nil
+	case len(a.PrivateKey) > 0:
+		key, err := PrivateKeyFromBytes(a.PrivateKey)
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+		return &key, nil
+	}
+	return nil, nil
+}
+
+// RestoreOptions configures Restore.
+type RestoreOptions struct {
+	// DryRun reports the changes Restore would make without applying them.
+	DryRun bool
+	// Passphrase opens the archive's private key if it is sealed.
+	Passphrase string
+	// SkipPrivateKey leaves the device's private key unchanged.
+	SkipPrivateKey bool
+}
+
+// Restore applies an archive to a device and returns the changes it made.
+// Only settings that differ from the archive are changed. Contacts and
+// channels on the device that are not in the archive are left in place,
+// except for channels in a slot the archive uses. With DryRun set, the
+// changes are returned without being applied.
+//
+// Importing a private key changes the device's identity, which takes effect
+// after the device is rebooted.
+func Restore(ctx context.Context, conn *Conn, a *Archive, opts *RestoreOptions) (*Plan, error) {
+	if opts == nil {
+		opts = &RestoreOptions{}
+	}
+
+	p, err := planRestore(ctx, conn, a, opts)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	if opts.DryRun {
+		return p, nil
+	}
+
+	if err := p.Apply(ctx, conn); err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	return p, nil
+}
+
+// config returns the parts of the archive that can be expressed as a Config.
+func (a *Archive) config() *Config {
+	info := a.SelfInfo
+	manual := info.ManualAddContacts != 0
+	radio := info.RadioParams()
+	return &Config{
+		Name:              &info.Name,
+		Location:          &Location{Lat: info.AdvLat, Lon: info.AdvLon},
+		TxPower:           &info.TxPower,
+		Radio:             &radio,
+		ManualAddContacts: &manual,
+	}
+}
+
+func planRestore(ctx context.Context, conn *Conn, a *Archive, opts *RestoreOptions) (*Plan, error) {
+	var p Plan
+
+	current, err := conn.GetSelfInfo(ctx)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	if !opts.SkipPrivateKey {
+		key, err := a.privateKey(opts.Passphrase)
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+		if key != nil {
+			if pub := key.PublicKey(); pub != current.PublicKey {
+				p.add(func(ctx context.Context, conn *Conn) error {
+					return conn.ImportPrivateKey(ctx, key.Bytes())
+				}, "private key: %s -> %s", current.PublicKey.String(), pub.String())
+			}
+		}
+	}
+
+	if err := a.config().planSelf(&p, current); err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	if want := a.TuningParams; want != nil {
+		params, err := conn.GetTuningParams(ctx)
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+		if *params != *want {
+			p.add(func(ctx context.Context, conn *Conn) error {
+				return conn.SetTuningParams(ctx, want)
+			}, "tuning: rx delay %g, airtime factor %g -> rx delay %g, airtime factor %g",
+				params.RxDelayBase, params.AirtimeFactor, want.RxDelayBase, want.AirtimeFactor)
+		}
+	}
+
+	contacts, err := conn.GetContacts(ctx, nil)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	existing := map[PublicKey]*Contact{}
+	for _, contact := range contacts {
+		existing[contact.PublicKey] = contact
+	}
+
+	for _, archived := range a.Contacts {
+		contact := archived.Contact
+		have, ok := existing[contact.PublicKey]
+		switch {
+		case !ok:
+			advert := archived.Advert
+			p.add(func(ctx context.Context, conn *Conn) error {
+				if len(advert) > 0 {
+					if err := conn.ImportContact(ctx, advert); err != nil {
+						return poop.Chain(err)
+					}
+				}
+				return conn.AddOrUpdateContact(ctx, contact)
+			}, "contact %q (%s): add", contact.AdvName, contact.PublicKey.String())
+		case !sameContact(have, contact):
+			p.add(func(ctx context.Context, conn *Conn) error {
+				return conn.AddOrUpdateContact(ctx, contact)
+			}, "contact %q (%s): update", contact.AdvName, contact.PublicKey.String())
+		}
+	}
+
+	channels, err := conn.GetChannels(ctx)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	for _, channel := range a.Channels {
+		if int(channel.Index) >= len(channels) {
+			return nil, poop.Newf(
+				"channel %q is in slot %d but the device only has %d slots",
+				channel.Name, channel.Index, len(channels))
+		}
+		planChannel(&p, channels[channel.Index], channel)
+	}
+
+	return &p, nil
+}
+
+// sameContact reports whether the settings of two contacts match, ignoring
+// timestamps.
+func sameContact(a, b *Contact) bool {
+	return a.Type == b.Type &&
+		a.Flags == b.Flags &&
+		slices.Equal(a.OutPath, b.OutPath) &&
+		a.AdvName == b.AdvName &&
+		a.AdvLat == b.AdvLat &&
+		a.AdvLon == b.AdvLon
+}
@@ -0,0 +1,203 @@
+package meshcore
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func configuredFakeDevice(t *testing.T) (*fakeDevice, PrivateKey) {
+	key, _ := fakePrivateKey(t, 42)
+
+	d := newFakeDevice(4)
+	d.info = SelfInfo{
+		Type:       1,
+		TxPower:    20,
+		MaxTxPower: 22,
+		PublicKey:  key.PublicKey(),
+		AdvLat:     37.7,
+		AdvLon:     122.4,
+		RadioFreq:  910.525,
+		RadioBw:    62.5,
+		RadioSf:    7,
+		RadioCr:    5,
+		Name:       "base",
+	}
+	d.tuning = &TuningParams{RxDelayBase: 0, AirtimeFactor: 1}
+	d.key = bytes.Clone(key.Bytes())
+
+	d.contacts = []*Contact{
+		{
+			PublicKey:  fakePublicKey(1),
+			Type:       ContactTypeChat,
+			OutPath:    []byte{1, 2},
+			AdvName:    "alice",
+			LastAdvert: time.Unix(100, 0),
+			LastMod:    time.Unix(100, 0),
+		},
+		{
+			PublicKey:  fakePublicKey(2),
+			Type:       ContactTypeRepeater,
+			AdvName:    "hilltop",
+			LastAdvert: time.Unix(200, 0),
+			LastMod:    time.Unix(200, 0),
+		},
+	}
+	d.adverts[fakePublicKey(1)] = []byte{0x11, 0x00, 1, 2, 3}
+
+	public := PublicChannel()
+	public.Index = 0
+	d.channels[0] = public
+	hashtag, err := HashtagChannel("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashtag.Index = 2
+	d.channels[2] = hashtag
+
+	return d, key
+}
+
+func TestBackup(t *testing.T) {
+	d, key := configuredFakeDevice(t)
+
+	var archive *Archive
+	controller := DoCommand(func(conn *Conn) {
+		var err error
+		archive, err = Backup(t.Context(), conn, &BackupOptions{Passphrase: "hunter2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	d.serve(t, controller)
+	controller.Wait()
+
+	if archive.Version != ArchiveVersion || archive.SelfInfo.Name != "base" {
+		t.Fatalf("unexpected archive: %s", describe(archive))
+	}
+	if !reflect.DeepEqual(archive.TuningParams, d.tuning) {
+		t.Fatalf("expected %s, got %s", describe(d.tuning), describe(archive.TuningParams))
+	}
+	if len(archive.Contacts) != 2 ||
+		!bytes.Equal(archive.Contacts[0].Advert, d.adverts[fakePublicKey(1)]) ||
+		archive.Contacts[1].Advert != nil {
+		t.Fatalf("unexpected contacts: %s", describe(archive.Contacts))
+	}
+	if len(archive.Channels) != 2 || archive.Channels[1].Index != 2 {
+		t.Fatalf("unexpected channels: %s", describe(archive.Channels))
+	}
+	if archive.PrivateKey != nil || archive.SealedPrivateKey == "" {
+		t.Fatal("expected a sealed private key")
+	}
+
+	var buf bytes.Buffer
+	if err := archive.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadArchive(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.Channels, archive.Channels) || read.SelfInfo.PublicKey != key.PublicKey() {
+		t.Fatalf("expected %s, got %s", describe(archive), describe(read))
+	}
+
+	if _, err := ReadArchive(strings.NewReader(`{"Version": 99}`)); err == nil || err.Error() != "unsupported archive version: 99" {
+		t.Fatalf("expected unsupported version error, got %v", err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	src, key := configuredFakeDevice(t)
+	src.key = nil
+
+	var archive *Archive
+	controller := DoCommand(func(conn *Conn) {
+		var err error
+		archive, err = Backup(t.Context(), conn, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	src.serve(t, controller)
+	controller.Wait()
+
+	if archive.PrivateKey != nil || archive.SealedPrivateKey != "" {
+		t.Fatal("expected no private key when export is disabled")
+	}
+	archive.PrivateKey = key.Bytes()
+
+	dst := newFakeDevice(4)
+	dst.info = SelfInfo{
+		PublicKey: fakePublicKey(9),
+		RadioFreq: 869.525,
+		RadioBw:   250,
+		RadioSf:   11,
+		RadioCr:   5,
+		Name:      "fresh",
+	}
+	dst.tuning = &TuningParams{}
+
+	restore := func(opts *RestoreOptions) []*Change {
+		var plan *Plan
+		controller := DoCommand(func(conn *Conn) {
+			var err error
+			plan, err = Restore(t.Context(), conn, archive, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+		dst.serve(t, controller)
+		controller.Wait()
+		return plan.Changes
+	}
+
+	changes := restore(&RestoreOptions{DryRun: true})
+	if len(changes) != 10 {
+		t.Fatalf("expected 10 changes, got %d: %v", len(changes), changes)
+	}
+	if changes[1].String() != `name: "fresh" -> "base"` {
+		t.Fatalf("unexpected change: %s", changes[1])
+	}
+	if dst.info.Name != "fresh" || dst.key != nil || len(dst.contacts) != 0 {
+		t.Fatal("dry run changed the device")
+	}
+
+	restore(nil)
+
+	if !bytes.Equal(dst.key, key.Bytes()) {
+		t.Fatal("expected private key to be imported")
+	}
+	// The identity and the hardware limits are not set by restore.
+	dst.info.Type = src.info.Type
+	dst.info.MaxTxPower = src.info.MaxTxPower
+	dst.info.PublicKey = key.PublicKey()
+	if !reflect.DeepEqual(dst.info, src.info) {
+		t.Fatalf("expected %s, got %s", describe(src.info), describe(dst.info))
+	}
+	if !reflect.DeepEqual(dst.tuning, src.tuning) {
+		t.Fatalf("expected %s, got %s", describe(src.tuning), describe(dst.tuning))
+	}
+	if len(dst.contacts) != 2 || len(dst.imported) != 1 {
+		t.Fatalf("unexpected contacts: %s", describe(dst.contacts))
+	}
+	if !reflect.DeepEqual(dst.channels, src.channels) {
+		t.Fatalf("expected %s, got %s", describe(src.channels), describe(dst.channels))
+	}
+
+	dst.commands = nil
+	if changes := restore(nil); len(changes) != 0 {
+		t.Fatalf("expected no changes after restore, got %v", changes)
+	}
+	if slices.ContainsFunc(dst.commands, func(code CommandCode) bool {
+		return code != CommandAppStart &&
+			code != CommandGetTuningParams &&
+			code != CommandGetContacts &&
+			code != CommandGetChannel
+	}) {
+		t.Fatalf("expected only reads, got %v", dst.commands)
+	}
+}
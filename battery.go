@@ -0,0 +1,325 @@
+package meshcore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kellegous/poop"
+)
+
+// BatteryPoint is a point on a discharge curve.
+type BatteryPoint struct {
+	Millivolts uint16
+	Percent    float64
+}
+
+// BatteryChemistry maps the voltage of a single cell to its state of charge.
+type BatteryChemistry struct {
+	Name string
+	// Curve is the discharge curve, ordered from full to empty.
+	Curve []BatteryPoint
+}
+
+var (
+	// LiPo is a lithium polymer or lithium ion cell.
+	LiPo = &BatteryChemistry{
+		Name: "LiPo",
+		Curve: []BatteryPoint{
+			{4200, 100}, {4150, 95}, {4110, 90}, {4080, 85}, {4020, 80},
+			{3980, 75}, {3950, 70}, {3910, 65}, {3870, 60}, {3850, 55},
+			{3840, 50}, {3820, 45}, {3800, 40}, {3790, 35}, {3770, 30},
+			{3750, 25}, {3730, 20}, {3710, 15}, {3690, 10}, {3610, 5},
+			{3270, 0},
+		},
+	}
+
+	// LiFePO4 is a lithium iron phosphate cell, whose curve is flat for most
+	// of its capacity.
+	LiFePO4 = &BatteryChemistry{
+		Name: "LiFePO4",
+		Curve: []BatteryPoint{
+			{3400, 100}, {3350, 99}, {3320, 90}, {3300, 70}, {3270, 40},
+			{3260, 30}, {3250, 20}, {3220, 17}, {3200, 14}, {3000, 9},
+			{2500, 0},
+		},
+	}
+)
+
+// Percent returns the state of charge for the voltage by interpolating along
+// the curve.
+func (c *BatteryChemistry) Percent(millivolts float64) float64 {
+	curve := c.Curve
+	if millivolts >= float64(curve[0].Millivolts) {
+		return curve[0].Percent
+	}
+
+	for i := 1; i < len(curve); i++ {
+		hi, lo := curve[i-1], curve[i]
+		if millivolts >= float64(lo.Millivolts) {
+			f := (millivolts - float64(lo.Millivolts)) / float64(hi.Millivolts-lo.Millivolts)
+			return lo.Percent + f*(hi.Percent-lo.Percent)
+		}
+	}
+
+	return curve[len(curve)-1].Percent
+}
+
+type BatteryEventType byte
+
+const (
+	// BatteryEventLow is raised when the charge drops below the low level.
+	BatteryEventLow BatteryEventType = iota + 1
+	// BatteryEventCritical is raised when the charge drops below the
+	// critical level.
+	BatteryEventCritical
+	// BatteryEventCharging is raised when the voltage starts to rise.
+	BatteryEventCharging
+)
+
+var batteryEventTypeText = map[BatteryEventType]string{
+	BatteryEventLow:      "low",
+	BatteryEventCritical: "critical",
+	BatteryEventCharging: "charging",
+}
+
+func (t BatteryEventType) String() string {
+	return batteryEventTypeText[t]
+}
+
+// BatteryEvent is raised by a BatteryMonitor when a battery's state changes.
+type BatteryEvent struct {
+	Type    BatteryEventType
+	Battery Battery
+}
+
+// Battery is the state of a battery tracked by a BatteryMonitor.
+type Battery struct {
+	// Key is the public key of the repeater the battery belongs to, or nil
+	// for the local device.
+	Key *PublicKey
+	// Millivolts is the smoothed voltage.
+	Millivolts float64
+	Percent    float64
+	Charging   bool
+	UpdatedAt  time.Time
+
+	level BatteryEventType
+}
+
+type BatteryOptions struct {
+	// Chemistry is used to convert voltage to a percentage. If nil, LiPo is
+	// used.
+	Chemistry *BatteryChemistry
+	// Repeaters are the remote repeaters whose batteries are read from their
+	// status by Poll.
+	Repeaters []PublicKey
+	// Interval is the time between polls made by Run. If zero, 5 minutes is
+	// used.
+	Interval time.Duration
+	// Smoothing is the weight, between 0 and 1, given to each new reading
+	// in an exponential moving average. If zero, 0.3 is used. Use 1 to
+	// disable smoothing.
+	Smoothing float64
+	// Low and Critical are the percentages below which the low and critical
+	// events are raised. If zero, 20 and 10 are used.
+	Low      float64
+	Critical float64
+	// ChargingDelta is the rise, in millivolts, of the smoothed voltage
+	// that indicates charging. If zero, 10 is used.
+	ChargingDelta float64
+	// OnEvent receives events. If nil, events are discarded.
+	OnEvent func(*BatteryEvent)
+	// OnError receives the errors from polls made by Run. If nil, those
+	// errors are discarded.
+	OnError func(error)
+}
+
+// batteryHysteresis is how far, in percent, the charge must rise above a
+// level before the level's event can be raised again.
+const batteryHysteresis = 5
+
+// BatteryMonitor tracks the batteries of a device and of remote repeaters.
+type BatteryMonitor struct {
+	conn *Conn
+	opts BatteryOptions
+	now  func() time.Time
+
+	mu        sync.Mutex
+	local     *Battery
+	repeaters map[PublicKey]*Battery
+}
+
+// NewBatteryMonitor creates a BatteryMonitor for the device on conn.
+func NewBatteryMonitor(conn *Conn, opts *BatteryOptions) *BatteryMonitor {
+	m := &BatteryMonitor{
+		conn:      conn,
+		now:       time.Now,
+		repeaters: map[PublicKey]*Battery{},
+	}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.Chemistry == nil {
+		m.opts.Chemistry = LiPo
+	}
+	if m.opts.Interval == 0 {
+		m.opts.Interval = 5 * time.Minute
+	}
+	if m.opts.Smoothing == 0 {
+		m.opts.Smoothing = 0.3
+	}
+	if m.opts.Low == 0 {
+		m.opts.Low = 20
+	}
+	if m.opts.Critical == 0 {
+		m.opts.Critical = 10
+	}
+	if m.opts.ChargingDelta == 0 {
+		m.opts.ChargingDelta = 10
+	}
+	return m
+}
+
+// Record adds a reading, in millivolts, for the battery of the repeater with
+// the given key, or of the local device if key is nil. Poll records readings
+// automatically.
+func (m *BatteryMonitor) Record(key *PublicKey, millivolts uint16) {
+	events := m.record(key, millivolts)
+	if m.opts.OnEvent == nil {
+		return
+	}
+	for _, event := range events {
+		m.opts.OnEvent(event)
+	}
+}
+
+func (m *BatteryMonitor) record(key *PublicKey, millivolts uint16) []*BatteryEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b := m.local
+	if key != nil {
+		b = m.repeaters[*key]
+	}
+
+	mv := float64(millivolts)
+	if b == nil {
+		b = &Battery{Millivolts: mv}
+		if key != nil {
+			k := *key
+			b.Key = &k
+			m.repeaters[k] = b
+		} else {
+			m.local = b
+		}
+	}
+
+	var events []*BatteryEvent
+	raise := func(t BatteryEventType) {
+		events = append(events, &BatteryEvent{Type: t, Battery: *b})
+	}
+
+	prev := b.Millivolts
+	b.Millivolts += m.opts.Smoothing * (mv - b.Millivolts)
+	b.Percent = m.opts.Chemistry.Percent(b.Millivolts)
+	b.UpdatedAt = m.now()
+
+	switch delta := b.Millivolts - prev; {
+	case delta >= m.opts.ChargingDelta && !b.Charging:
+		b.Charging = true
+		raise(BatteryEventCharging)
+	case delta < 0:
+		b.Charging = false
+	}
+
+	switch {
+	case b.Percent < m.opts.Critical:
+		if b.level != BatteryEventCritical {
+			b.level = BatteryEventCritical
+			raise(BatteryEventCritical)
+		}
+	case b.Percent < m.opts.Low:
+		if b.level == 0 {
+			b.level = BatteryEventLow
+			raise(BatteryEventLow)
+		} else if b.level == BatteryEventCritical && b.Percent >= m.opts.Critical+batteryHysteresis {
+			b.level = BatteryEventLow
+		}
+	case b.Percent >= m.opts.Low+batteryHysteresis:
+		b.level = 0
+	}
+
+	return events
+}
+
+// Batteries returns the state of the tracked batteries, starting with the
+// local device's.
+func (m *BatteryMonitor) Batteries() []Battery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var batteries []Battery
+	if m.local != nil {
+		batteries = append(batteries, *m.local)
+	}
+	for _, key := range m.opts.Repeaters {
+		if b := m.repeaters[key]; b != nil {
+			batteries = append(batteries, *b)
+		}
+	}
+	return batteries
+}
+
+// Poll reads the battery of the device and of each repeater. A battery that
+// cannot be read is skipped; the errors are joined and returned once all have
+// been tried.
+func (m *BatteryMonitor) Poll(ctx context.Context) error {
+	var errs []error
+	if mv, err := m.conn.GetBatteryVoltage(ctx); err != nil {
+		errs = append(errs, poop.ChainWith(err, "device"))
+	} else {
+		m.Record(nil, mv)
+	}
+
+	for _, key := range m.opts.Repeaters {
+		if err := m.pollRepeater(ctx, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *BatteryMonitor) pollRepeater(ctx context.Context, key PublicKey) error {
+	status, err := m.conn.GetStatus(ctx, key)
+	if err != nil {
+		return poop.ChainWithf(err, "repeater %s", &key)
+	}
+
+	stats, err := status.RepeaterStats()
+	if err != nil {
+		return poop.ChainWithf(err, "repeater %s", &key)
+	}
+
+	m.Record(&key, stats.BatteryMillivolts)
+	return nil
+}
+
+// Run polls immediately, then after each interval, until ctx is done.
+func (m *BatteryMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Poll(ctx); err != nil && ctx.Err() == nil && m.opts.OnError != nil {
+			m.opts.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return poop.Chain(ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package meshcore
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestBatteryChemistryPercent(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Chemistry  *BatteryChemistry
+		Millivolts float64
+		Expected   float64
+	}{
+		{Name: "lipo full", Chemistry: LiPo, Millivolts: 4250, Expected: 100},
+		{Name: "lipo point", Chemistry: LiPo, Millivolts: 3840, Expected: 50},
+		{Name: "lipo between", Chemistry: LiPo, Millivolts: 4050, Expected: 82.5},
+		{Name: "lipo empty", Chemistry: LiPo, Millivolts: 3000, Expected: 0},
+		{Name: "lifepo4 plateau", Chemistry: LiFePO4, Millivolts: 3285, Expected: 55},
+		{Name: "lifepo4 empty", Chemistry: LiFePO4, Millivolts: 2400, Expected: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if p := test.Chemistry.Percent(test.Millivolts); math.Abs(p-test.Expected) > 1e-9 {
+				t.Fatalf("expected %f, got %f", test.Expected, p)
+			}
+		})
+	}
+}
+
+func TestBatteryMonitorEvents(t *testing.T) {
+	var events []BatteryEventType
+	m := NewBatteryMonitor(nil, &BatteryOptions{
+		Smoothing: 1,
+		OnEvent: func(e *BatteryEvent) {
+			events = append(events, e.Type)
+		},
+	})
+
+	key := fakePublicKey(7)
+	for _, mv := range []uint16{3900, 3720, 3700, 3650, 3800, 3850, 3720} {
+		m.Record(&key, mv)
+	}
+
+	expected := []BatteryEventType{
+		BatteryEventLow,
+		BatteryEventCritical,
+		BatteryEventCharging,
+		BatteryEventLow,
+	}
+	if !slices.Equal(events, expected) {
+		t.Fatalf("expected %v, got %v", expected, events)
+	}
+
+	batteries := m.Batteries()
+	if len(batteries) != 0 {
+		t.Fatalf("expected untracked repeater to be omitted, got %s", describe(batteries))
+	}
+}
+
+func TestBatteryMonitorSmoothing(t *testing.T) {
+	m := NewBatteryMonitor(nil, &BatteryOptions{Smoothing: 0.5})
+	m.Record(nil, 4000)
+	m.Record(nil, 3900)
+
+	batteries := m.Batteries()
+	if len(batteries) != 1 || batteries[0].Key != nil || batteries[0].Millivolts != 3950 {
+		t.Fatalf("unexpected batteries: %s", describe(batteries))
+	}
+	if batteries[0].Percent != LiPo.Percent(3950) {
+		t.Fatalf("expected %f, got %f", LiPo.Percent(3950), batteries[0].Percent)
+	}
+}
+
+func TestBatteryMonitorPoll(t *testing.T) {
+	key := fakePublicKey(42)
+
+	var m *BatteryMonitor
+	controller := DoCommand(func(conn *Conn) {
+		m = NewBatteryMonitor(conn, &BatteryOptions{
+			Chemistry: LiFePO4,
+			Repeaters: []PublicKey{key},
+		})
+		if err := m.Poll(t.Context()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if err := ValidateBytes(controller.Recv(), Command(CommandGetBatteryVoltage)); err != nil {
+		t.Fatal(err)
+	}
+	controller.Notify(NotificationTypeBatteryVoltage, BytesFrom(Uint16(4100, binary.LittleEndian)))
+
+	if err := ValidateBytes(
+		controller.Recv(),
+		Command(CommandSendStatusReq),
+		Bytes(key.Bytes()...),
+	); err != nil {
+		t.Fatal(err)
+	}
+	controller.Notify(NotificationTypeStatus, BytesFrom(
+		Byte(0),
+		Bytes(key.Prefix(6)...),
+		Uint16(3300, binary.LittleEndian),
+	))
+
+	controller.Wait()
+
+	batteries := m.Batteries()
+	if len(batteries) != 2 {
+		t.Fatalf("expected 2 batteries, got %s", describe(batteries))
+	}
+	if batteries[0].Key != nil || batteries[0].Percent != 100 {
+		t.Fatalf("unexpected local battery: %s", describe(batteries[0]))
+	}
+	if *batteries[1].Key != key || batteries[1].Percent != 70 {
+		t.Fatalf("unexpected repeater battery: %s", describe(batteries[1]))
+	}
+}
+
+func TestBatteryMonitorPollLocalError(t *testing.T) {
+	key := fakePublicKey(42)
+
+	var m *BatteryMonitor
+	controller := DoCommand(func(conn *Conn) {
+		m = NewBatteryMonitor(conn, &BatteryOptions{
+			Chemistry: LiFePO4,
+			Repeaters: []PublicKey{key},
+		})
+		if err := m.Poll(t.Context()); err == nil {
+			t.Fatal("expected the local battery error")
+		}
+	})
+
+	if err := ValidateBytes(controller.Recv(), Command(CommandGetBatteryVoltage)); err != nil {
+		t.Fatal(err)
+	}
+	controller.Notify(NotificationTypeErr, BytesFrom(Byte(byte(ErrorCodeFileIOError))))
+
+	if err := ValidateBytes(
+		controller.Recv(),
+		Command(CommandSendStatusReq),
+		Bytes(key.Bytes()...),
+	); err != nil {
+		t.Fatal(err)
+	}
+	controller.Notify(NotificationTypeStatus, BytesFrom(
+		Byte(0),
+		Bytes(key.Prefix(6)...),
+		Uint16(3300, binary.LittleEndian),
+	))
+
+	controller.Wait()
+
+	batteries := m.Batteries()
+	if len(batteries) != 1 || *batteries[0].Key != key {
+		t.Fatalf("expected only the repeater battery, got %s", describe(batteries))
+	}
+}
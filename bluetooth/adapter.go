@@ -0,0 +1,145 @@
+package bluetooth
+
+import (
+	"tinygo.org/x/bluetooth"
+)
+
+// ScanResult is a device found by a scan.
+type ScanResult struct {
+	Address   bluetooth.Address
+	LocalName string
+	// RSSI is the signal strength of the advertisement in dBm.
+	RSSI int16
+	// SeenBefore is set if an earlier scan by the same Client found the
+	// device.
+	SeenBefore bool
+}
+
+// Adapter is a Bluetooth adapter that scans for and connects to devices.
+type Adapter interface {
+	Enable() error
+	// Scan calls callback with each advertisement it receives until StopScan
+	// is called.
+	Scan(callback func(*ScanResult)) error
+	StopScan() error
+	Connect(address bluetooth.Address) (Device, error)
+	// SetConnectHandler calls handler when a device connects or disconnects,
+	// including when the link drops.
+	SetConnectHandler(handler func(address bluetooth.Address, connected bool))
+}
+
+// Device is a connected peripheral.
+type Device interface {
+	DiscoverServices(uuids []bluetooth.UUID) ([]Service, error)
+	Disconnect() error
+}
+
+// Service is a GATT service of a Device.
+type Service interface {
+	DiscoverCharacteristics(uuids []bluetooth.UUID) ([]Characteristic, error)
+}
+
+// Characteristic is a GATT characteristic of a Service.
+type Characteristic interface {
+	UUID() bluetooth.UUID
+	Write(p []byte) (int, error)
+	// GetMTU returns the ATT MTU of the connection.
+	GetMTU() (uint16, error)
+	EnableNotifications(callback func(data []byte)) error
+}
+
+// NewAdapter returns an Adapter backed by a tinygo adapter, such as
+// bluetooth.DefaultAdapter.
+func NewAdapter(a *bluetooth.Adapter) Adapter {
+	return &adapter{a: a}
+}
+
+type adapter struct {
+	a *bluetooth.Adapter
+}
+
+func (a *adapter) Enable() error {
+	return a.a.Enable()
+}
+
+func (a *adapter) Scan(callback func(*ScanResult)) error {
+	return a.a.Scan(func(_ *bluetooth.Adapter, result bluetooth.ScanResult) {
+		callback(&ScanResult{
+			Address:   result.Address,
+			LocalName: result.LocalName(),
+			RSSI:      result.RSSI,
+		})
+	})
+}
+
+func (a *adapter) StopScan() error {
+	return a.a.StopScan()
+}
+
+func (a *adapter) SetConnectHandler(handler func(address bluetooth.Address, connected bool)) {
+	a.a.SetConnectHandler(func(device bluetooth.Device, connected bool) {
+		handler(device.Address, connected)
+	})
+}
+
+func (a *adapter) Connect(address bluetooth.Address) (Device, error) {
+	d, err := a.a.Connect(address, bluetooth.ConnectionParams{})
+	if err != nil {
+		return nil, err
+	}
+	return &device{d: d}, nil
+}
+
+type device struct {
+	d bluetooth.Device
+}
+
+func (d *device) DiscoverServices(uuids []bluetooth.UUID) ([]Service, error) {
+	ss, err := d.d.DiscoverServices(uuids)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]Service, 0, len(ss))
+	for _, s := range ss {
+		services = append(services, &service{s: s})
+	}
+	return services, nil
+}
+
+func (d *device) Disconnect() error {
+	return d.d.Disconnect()
+}
+
+type service struct {
+	s bluetooth.DeviceService
+}
+
+func (s *service) DiscoverCharacteristics(uuids []bluetooth.UUID) ([]Characteristic, error) {
+	cs, err := s.s.DiscoverCharacteristics(uuids)
+	if err != nil {
+		return nil, err
+	}
+
+	characteristics := make([]Characteristic, 0, len(cs))
+	for _, c := range cs {
+		characteristics = append(characteristics, &characteristic{c: c})
+	}
+	return characteristics, nil
+}
+
+type characteristic struct {
+	c bluetooth.DeviceCharacteristic
+}
+
+func (c *characteristic) UUID() bluetooth.UUID {
+	return c.c.UUID()
+}
+
+func (c *characteristic) GetMTU() (uint16, error) {
+	return c.c.GetMTU()
+}
+
+func (c *characteristic) EnableNotifications(callback func(data []byte)) error {
+	return c.c.EnableNotifications(callback)
+}
@@ -0,0 +1,7 @@
+package bluetooth
+
+// Write writes to the characteristic. BlueZ chooses between a write request
+// and a write command from the characteristic's properties.
+func (c *characteristic) Write(p []byte) (int, error) {
+	return c.c.WriteWithoutResponse(p)
+}
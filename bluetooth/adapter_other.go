@@ -0,0 +1,7 @@
+//go:build !linux
+
+package bluetooth
+
+func (c *characteristic) Write(p []byte) (int, error) {
+	return c.c.Write(p)
+}
@@ -0,0 +1,9 @@
+package bluetooth
+
+import "tinygo.org/x/bluetooth"
+
+func fakeAddress(id byte) bluetooth.Address {
+	return bluetooth.Address{
+		UUID: bluetooth.NewUUID([16]byte{15: id}),
+	}
+}
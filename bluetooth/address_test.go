@@ -0,0 +1,11 @@
+//go:build !darwin
+
+package bluetooth
+
+import "tinygo.org/x/bluetooth"
+
+func fakeAddress(id byte) bluetooth.Address {
+	return bluetooth.Address{
+		MACAddress: bluetooth.MACAddress{MAC: bluetooth.MAC{id, 0, 0, 0, 0, 0xaa}},
+	}
+}
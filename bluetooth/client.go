@@ -3,7 +3,9 @@ package bluetooth
 import (
 	"context"
 	"iter"
+	"log/slog"
 	"strings"
+	"sync"
 
 	"github.com/kellegous/poop"
 	"tinygo.org/x/bluetooth"
@@ -26,35 +28,95 @@ func mustParseUUID(s string) bluetooth.UUID {
 }
 
 type Client struct {
-	adapter *bluetooth.Adapter
+	adapter Adapter
+
+	mu sync.Mutex
+	// conns are the open connections by address, so link changes reported
+	// by the adapter reach them.
+	conns map[string]*tx
+	// seen holds the addresses of every device found by a scan.
+	seen map[string]bool
 }
 
+// NewClient returns a Client that uses a tinygo adapter, such as
+// bluetooth.DefaultAdapter.
 func NewClient(adapter *bluetooth.Adapter) (*Client, error) {
+	return NewClientWithAdapter(NewAdapter(adapter))
+}
+
+// NewClientWithAdapter returns a Client that uses adapter.
+func NewClientWithAdapter(adapter Adapter) (*Client, error) {
 	if err := adapter.Enable(); err != nil {
 		return nil, poop.Chain(err)
 	}
-	return &Client{adapter: adapter}, nil
+
+	c := &Client{
+		adapter: adapter,
+		conns:   make(map[string]*tx),
+		seen:    make(map[string]bool),
+	}
+	adapter.SetConnectHandler(c.onConnectChange)
+	return c, nil
+}
+
+func (c *Client) onConnectChange(address bluetooth.Address, connected bool) {
+	if connected {
+		return
+	}
+
+	c.mu.Lock()
+	t := c.conns[address.String()]
+	c.mu.Unlock()
+
+	if t != nil {
+		t.lost()
+	}
+}
+
+func (c *Client) register(t *tx) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conns[t.address.String()] = t
+}
+
+func (c *Client) unregister(t *tx) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conns[t.address.String()] == t {
+		delete(c.conns, t.address.String())
+	}
 }
 
-func isMeshcoreDevice(result *bluetooth.ScanResult) bool {
-	return strings.HasPrefix(result.LocalName(), "MeshCore-")
+// markSeen records that a scan found result and reports whether an earlier
+// scan had found it.
+func (c *Client) markSeen(result *ScanResult) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seen := c.seen[result.Address.String()]
+	c.seen[result.Address.String()] = true
+	return seen
 }
 
-func (c *Client) LookupDevice(ctx context.Context, name string) (*bluetooth.ScanResult, error) {
+func isMeshcoreDevice(result *ScanResult) bool {
+	return strings.HasPrefix(result.LocalName, "MeshCore-")
+}
+
+// LookupDevice finds the device with the given local name or address.
+func (c *Client) LookupDevice(ctx context.Context, name string) (*ScanResult, error) {
 	for device, err := range c.DiscoverDevices(ctx) {
 		if err != nil {
 			return nil, poop.Chain(err)
 		}
 
-		if device.LocalName() == name {
+		if device.LocalName == name || strings.EqualFold(device.Address.String(), name) {
 			return device, nil
 		}
 	}
 	return nil, poop.Newf("device %s not found", name)
 }
 
-func (c *Client) DiscoverDevices(ctx context.Context) iter.Seq2[*bluetooth.ScanResult, error] {
-	return func(yield func(*bluetooth.ScanResult, error) bool) {
+func (c *Client) DiscoverDevices(ctx context.Context) iter.Seq2[*ScanResult, error] {
+	return func(yield func(*ScanResult, error) bool) {
 		seen := make(map[string]bool)
 
 		go func() {
@@ -62,13 +124,14 @@ func (c *Client) DiscoverDevices(ctx context.Context) iter.Seq2[*bluetooth.ScanR
 			c.adapter.StopScan()
 		}()
 
-		if err := c.adapter.Scan(func(adapter *bluetooth.Adapter, result bluetooth.ScanResult) {
-			if !isMeshcoreDevice(&result) || seen[result.Address.String()] {
+		if err := c.adapter.Scan(func(result *ScanResult) {
+			if !isMeshcoreDevice(result) || seen[result.Address.String()] {
 				return
 			}
 
 			seen[result.Address.String()] = true
-			if !yield(&result, nil) {
+			result.SeenBefore = c.markSeen(result)
+			if !yield(result, nil) {
 				c.adapter.StopScan()
 			}
 		}); err != nil {
@@ -87,43 +150,58 @@ func (c *Client) Connect(
 		opt(options)
 	}
 
-	device, err := c.adapter.Connect(address, bluetooth.ConnectionParams{})
-	if err != nil {
+	logger := options.logger().With(slog.String("address", address.String()))
+
+	t := &tx{
+		client:             c,
+		address:            address,
+		NotificationCenter: meshcore.NewNotificationCenter(),
+		options:            options,
+		logger:             logger,
+		state:              StateDisconnected,
+		stop:               make(chan struct{}),
+	}
+
+	c.register(t)
+	if err := t.connect(); err != nil {
+		c.unregister(t)
 		return nil, poop.Chain(err)
 	}
 
+	conn := meshcore.NewConnection(t)
+	if options.log != nil {
+		conn.SetLogging(options.log)
+	}
+	conn.Use(options.middleware...)
+	return conn, nil
+}
+
+// discoverCharacteristics finds the characteristics of the UART service
+// that carry frames to and from the device.
+func discoverCharacteristics(device Device) (toDevice, frDevice Characteristic, err error) {
 	services, err := device.DiscoverServices([]bluetooth.UUID{serviceUUID})
 	if err != nil {
-		return nil, poop.Chain(err)
+		return nil, nil, poop.Chain(err)
 	}
 	if len(services) != 1 {
-		return nil, poop.Newf("expected 1 service, got %d", len(services))
+		return nil, nil, poop.Newf("expected 1 service, got %d", len(services))
 	}
 
 	characteristics, err := services[0].DiscoverCharacteristics([]bluetooth.UUID{toDeviceUUID, frDeviceUUID})
 	if err != nil {
-		return nil, poop.Chain(err)
+		return nil, nil, poop.Chain(err)
 	}
-	if len(characteristics) != 2 {
-		return nil, poop.Newf("expected 2 characteristics, got %d", len(characteristics))
-	}
-
-	toDevice, frDevice := characteristics[0], characteristics[1]
 
-	notificationCenter := meshcore.NewNotificationCenter()
-
-	frDevice.EnableNotifications(func(data []byte) {
-		code := meshcore.NotificationCode(data[0])
-		if nf := options.onRecv; nf != nil {
-			nf(code, data[1:])
+	for _, c := range characteristics {
+		switch c.UUID() {
+		case toDeviceUUID:
+			toDevice = c
+		case frDeviceUUID:
+			frDevice = c
 		}
-		notificationCenter.Publish(code, data[1:])
-	})
-
-	return meshcore.NewConnection(&tx{
-		device:             device,
-		toDevice:           toDevice,
-		NotificationCenter: notificationCenter,
-		opts:               options,
-	}), nil
+	}
+	if toDevice == nil || frDevice == nil {
+		return nil, nil, poop.Newf("expected 2 characteristics, got %d", len(characteristics))
+	}
+	return toDevice, frDevice, nil
 }
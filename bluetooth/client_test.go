@@ -0,0 +1,298 @@
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kellegous/meshcore"
+)
+
+func newFakeClient(t *testing.T, peripherals ...*fakePeripheral) *Client {
+	client, err := NewClientWithAdapter(newFakeAdapter(peripherals...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestDiscoverDevices(t *testing.T) {
+	client := newFakeClient(t,
+		newFakePeripheral(1, "MeshCore-a"),
+		newFakePeripheral(2, "Headphones"),
+		newFakePeripheral(3, "MeshCore-b"),
+	)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	var names []string
+	for device, err := range client.DiscoverDevices(ctx) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, device.LocalName)
+	}
+
+	if expected := []string{"MeshCore-a", "MeshCore-b"}; !slices.Equal(names, expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestDiscoverDevicesSeenBefore(t *testing.T) {
+	client := newFakeClient(t, newFakePeripheral(1, "MeshCore-a"))
+
+	for _, expected := range []bool{false, true} {
+		device, err := client.LookupDevice(t.Context(), "MeshCore-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if device.SeenBefore != expected {
+			t.Fatalf("expected SeenBefore to be %t, got %t", expected, device.SeenBefore)
+		}
+	}
+}
+
+func TestLookupDevice(t *testing.T) {
+	b := newFakePeripheral(2, "MeshCore-b")
+	client := newFakeClient(t, newFakePeripheral(1, "MeshCore-a"), b)
+
+	for _, name := range []string{"MeshCore-b", b.address.String()} {
+		device, err := client.LookupDevice(t.Context(), name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if device.Address != b.address || device.LocalName != "MeshCore-b" || device.RSSI != -60 {
+			t.Fatalf("expected MeshCore-b, got %+v", device)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.LookupDevice(ctx, "MeshCore-c"); err == nil {
+		t.Fatal("expected an error for a missing device")
+	}
+}
+
+func TestConnect(t *testing.T) {
+	p := newFakePeripheral(1, "MeshCore-a")
+	client := newFakeClient(t, p)
+
+	conn, err := client.Connect(t.Context(), p.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		v, err := conn.GetBatteryVoltage(t.Context())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if v != 3987 {
+			t.Errorf("expected 3987, got %d", v)
+		}
+	}()
+
+	if frame := p.Recv(); !slices.Equal(frame, []byte{byte(meshcore.CommandGetBatteryVoltage)}) {
+		t.Fatalf("expected GetBatteryVoltage, got %x", frame)
+	}
+	p.Notify([]byte{byte(meshcore.NotificationTypeBatteryVoltage), 0x93, 0x0f})
+	<-done
+
+	if err := conn.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if p.isConnected() {
+		t.Fatal("expected the peripheral to be disconnected")
+	}
+
+	if _, err := conn.GetBatteryVoltage(t.Context()); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("expected %v, got %v", ErrNotConnected, err)
+	}
+}
+
+func TestConnectErrors(t *testing.T) {
+	noService := newFakePeripheral(1, "MeshCore-a")
+	noService.noService = true
+	client := newFakeClient(t, noService)
+
+	if _, err := client.Connect(t.Context(), fakeAddress(9)); err == nil {
+		t.Fatal("expected an error for an unknown address")
+	}
+
+	_, err := client.Connect(t.Context(), noService.address)
+	if err == nil || err.Error() != "expected 1 service, got 0" {
+		t.Fatalf("expected a service error, got %v", err)
+	}
+	if noService.isConnected() {
+		t.Fatal("expected the peripheral to be disconnected after a failed connect")
+	}
+}
+
+// stateRecorder collects the states passed to OnStateChange.
+type stateRecorder struct {
+	ch chan ConnectionState
+}
+
+func newStateRecorder() *stateRecorder {
+	return &stateRecorder{ch: make(chan ConnectionState, 16)}
+}
+
+func (r *stateRecorder) record(state ConnectionState) {
+	r.ch <- state
+}
+
+func (r *stateRecorder) expect(t *testing.T, states ...ConnectionState) {
+	t.Helper()
+	for _, expected := range states {
+		select {
+		case state := <-r.ch:
+			if state != expected {
+				t.Fatalf("expected %s, got %s", expected, state)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected %s, got nothing", expected)
+		}
+	}
+}
+
+func (r *stateRecorder) expectNone(t *testing.T) {
+	t.Helper()
+	select {
+	case state := <-r.ch:
+		t.Fatalf("expected no state change, got %s", state)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWriteMTU(t *testing.T) {
+	p := newFakePeripheral(1, "MeshCore-a")
+	client := newFakeClient(t, p)
+
+	conn, err := client.Connect(t.Context(), p.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Disconnect()
+
+	client.mu.Lock()
+	tx := client.conns[p.address.String()]
+	client.mu.Unlock()
+
+	// An MTU of 23 leaves 20 bytes for each write. The device takes each
+	// write as a whole frame, so a larger one fails rather than being split.
+	frame := append([]byte{byte(meshcore.CommandSendChannelTxtMsg)}, strings.Repeat("x", 32)...)
+	if _, err := tx.Write(frame); err == nil {
+		t.Fatal("expected a frame larger than the MTU to fail")
+	}
+	if n := len(p.frames); n != 0 {
+		t.Fatalf("expected nothing to be written, got %d writes", n)
+	}
+
+	// Once a larger MTU is negotiated, the frame fits in one write.
+	p.SetMTU(185)
+	if _, err := tx.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	if written := p.Recv(); !slices.Equal(written, frame) {
+		t.Fatalf("expected the whole frame in one write, got %x", written)
+	}
+}
+
+func TestEmptyNotification(t *testing.T) {
+	p := newFakePeripheral(1, "MeshCore-a")
+	client := newFakeClient(t, p)
+
+	conn, err := client.Connect(t.Context(), p.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Disconnect()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := conn.GetBatteryVoltage(t.Context()); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	p.Recv()
+	p.Notify(nil)
+	p.Notify([]byte{byte(meshcore.NotificationTypeBatteryVoltage), 0x93, 0x0f})
+	<-done
+}
+
+func TestLinkLost(t *testing.T) {
+	p := newFakePeripheral(1, "MeshCore-a")
+	client := newFakeClient(t, p)
+	states := newStateRecorder()
+
+	conn, err := client.Connect(t.Context(), p.address, OnStateChange(states.record))
+	if err != nil {
+		t.Fatal(err)
+	}
+	states.expect(t, StateConnected)
+
+	p.Drop()
+	states.expect(t, StateDisconnected)
+	states.expectNone(t)
+
+	if _, err := conn.GetBatteryVoltage(t.Context()); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("expected %v, got %v", ErrNotConnected, err)
+	}
+}
+
+func TestAutoReconnect(t *testing.T) {
+	p := newFakePeripheral(1, "MeshCore-a")
+	client := newFakeClient(t, p)
+	states := newStateRecorder()
+
+	conn, err := client.Connect(
+		t.Context(),
+		p.address,
+		OnStateChange(states.record),
+		AutoReconnect(time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	states.expect(t, StateConnected)
+
+	p.Refuse(1)
+	p.Drop()
+	states.expect(
+		t,
+		StateDisconnected,
+		StateReconnecting,
+		StateReconnecting,
+		StateConnected,
+	)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := conn.GetBatteryVoltage(t.Context()); err != nil {
+			t.Error(err)
+		}
+	}()
+	p.Recv()
+	p.Notify([]byte{byte(meshcore.NotificationTypeBatteryVoltage), 0x93, 0x0f})
+	<-done
+
+	// Disconnecting on purpose does not reconnect.
+	if err := conn.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	states.expect(t, StateDisconnected)
+	states.expectNone(t)
+	if p.isConnected() {
+		t.Fatal("expected the peripheral to stay disconnected")
+	}
+}
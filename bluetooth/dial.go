@@ -0,0 +1,46 @@
+package bluetooth
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/kellegous/poop"
+	"tinygo.org/x/bluetooth"
+
+	"github.com/kellegous/meshcore"
+)
+
+func init() {
+	meshcore.RegisterDialer("ble", dial)
+}
+
+// dial connects to a URL like ble://MeshCore-abc or ble://AA:BB:CC:DD:EE:FF
+// using the default adapter. The query may set reconnect to the delay before
+// reconnecting when the link drops, like 1s.
+func dial(ctx context.Context, addr string, query url.Values) (*meshcore.Conn, error) {
+	if err := meshcore.CheckDialQuery(query, "reconnect"); err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	var opts []ConnectOption
+	if v := query.Get("reconnect"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, poop.Newf("invalid reconnect delay: %q", v)
+		}
+		opts = append(opts, AutoReconnect(d))
+	}
+
+	client, err := NewClient(bluetooth.DefaultAdapter)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	device, err := client.LookupDevice(ctx, addr)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	return client.Connect(ctx, device.Address, opts...)
+}
@@ -3,8 +3,10 @@ package bluetooth_test
 import (
 	"context"
 	"log"
+	"sync/atomic"
 	"time"
 
+	"github.com/kellegous/meshcore"
 	meshcore_bluetooth "github.com/kellegous/meshcore/bluetooth"
 	"tinygo.org/x/bluetooth"
 )
@@ -56,3 +58,44 @@ func ExampleClient_DiscoverDevices() {
 	}
 	defer conn.Disconnect()
 }
+
+// Keep the device's clock in sync, checking it again whenever the link is
+// reestablished.
+func ExampleOnStateChange() {
+	ctx := context.Background()
+
+	client, err := meshcore_bluetooth.NewClient(bluetooth.DefaultAdapter)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	device, err := client.LookupDevice(ctx, "MeshCore-1234567890")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The TimeSync needs the connection, which does not exist yet when the
+	// option is created.
+	var timeSync atomic.Pointer[meshcore.TimeSync]
+
+	conn, err := client.Connect(
+		ctx,
+		device.Address,
+		meshcore_bluetooth.AutoReconnect(time.Second),
+		meshcore_bluetooth.OnStateChange(func(state meshcore_bluetooth.ConnectionState) {
+			if s := timeSync.Load(); s != nil && state == meshcore_bluetooth.StateConnected {
+				s.Trigger()
+			}
+		}),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Disconnect()
+
+	s := meshcore.NewTimeSync(conn, nil)
+	timeSync.Store(s)
+	if err := s.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
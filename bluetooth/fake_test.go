@@ -0,0 +1,265 @@
+package bluetooth
+
+import (
+	"errors"
+	"slices"
+	"sync"
+
+	"tinygo.org/x/bluetooth"
+)
+
+var errNotConnected = errors.New("not connected")
+
+// fakeAdapter is an Adapter whose scans find its peripherals.
+type fakeAdapter struct {
+	mu          sync.Mutex
+	peripherals []*fakePeripheral
+	stop        chan struct{}
+	handler     func(bluetooth.Address, bool)
+}
+
+var _ Adapter = (*fakeAdapter)(nil)
+
+func newFakeAdapter(peripherals ...*fakePeripheral) *fakeAdapter {
+	a := &fakeAdapter{peripherals: peripherals}
+	for _, p := range peripherals {
+		p.adapter = a
+	}
+	return a
+}
+
+func (a *fakeAdapter) Enable() error {
+	return nil
+}
+
+func (a *fakeAdapter) Scan(callback func(*ScanResult)) error {
+	stop := make(chan struct{})
+	a.mu.Lock()
+	a.stop = stop
+	peripherals := slices.Clone(a.peripherals)
+	a.mu.Unlock()
+
+	for _, p := range peripherals {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		// Each peripheral advertises twice, as real ones do.
+		for range 2 {
+			callback(&ScanResult{Address: p.address, LocalName: p.name, RSSI: p.rssi})
+		}
+	}
+
+	<-stop
+	return nil
+}
+
+func (a *fakeAdapter) StopScan() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stop != nil {
+		close(a.stop)
+		a.stop = nil
+	}
+	return nil
+}
+
+func (a *fakeAdapter) SetConnectHandler(handler func(bluetooth.Address, bool)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.handler = handler
+}
+
+// connectChanged calls the connect handler, as the stack does when a link
+// comes up or goes down.
+func (a *fakeAdapter) connectChanged(address bluetooth.Address, connected bool) {
+	a.mu.Lock()
+	handler := a.handler
+	a.mu.Unlock()
+	if handler != nil {
+		handler(address, connected)
+	}
+}
+
+func (a *fakeAdapter) Connect(address bluetooth.Address) (Device, error) {
+	a.mu.Lock()
+	peripherals := slices.Clone(a.peripherals)
+	a.mu.Unlock()
+
+	for _, p := range peripherals {
+		if p.address == address {
+			if err := p.accept(); err != nil {
+				return nil, err
+			}
+			a.connectChanged(address, true)
+			return &fakeDevice{p: p}, nil
+		}
+	}
+	return nil, errors.New("device not found")
+}
+
+// fakePeripheral is a MeshCore companion that serves the UART service. The
+// frames written to it are received with Recv and it sends notifications
+// with Notify.
+type fakePeripheral struct {
+	name      string
+	address   bluetooth.Address
+	rssi      int16
+	noService bool
+	adapter   *fakeAdapter
+
+	frames chan []byte
+
+	mu        sync.Mutex
+	connected bool
+	mtu       uint16
+	notify    func([]byte)
+	// refuse is how many more connects will fail.
+	refuse int
+}
+
+func newFakePeripheral(id byte, name string) *fakePeripheral {
+	return &fakePeripheral{
+		name:    name,
+		address: fakeAddress(id),
+		rssi:    -60,
+		mtu:     23,
+		frames:  make(chan []byte, 8),
+	}
+}
+
+func (p *fakePeripheral) setConnected(connected bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.connected = connected
+	if !connected {
+		p.notify = nil
+	}
+}
+
+func (p *fakePeripheral) accept() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.refuse > 0 {
+		p.refuse--
+		return errors.New("connection refused")
+	}
+	p.connected = true
+	return nil
+}
+
+// SetMTU changes the MTU, as when a larger one is negotiated.
+func (p *fakePeripheral) SetMTU(mtu uint16) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mtu = mtu
+}
+
+// Refuse makes the next n connects fail.
+func (p *fakePeripheral) Refuse(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refuse = n
+}
+
+// Drop drops the link, as when the device goes out of range or reboots.
+func (p *fakePeripheral) Drop() {
+	p.setConnected(false)
+	p.adapter.connectChanged(p.address, false)
+}
+
+func (p *fakePeripheral) isConnected() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.connected
+}
+
+func (p *fakePeripheral) Recv() []byte {
+	return <-p.frames
+}
+
+func (p *fakePeripheral) Notify(data []byte) {
+	p.mu.Lock()
+	notify := p.notify
+	p.mu.Unlock()
+	if notify != nil {
+		notify(data)
+	}
+}
+
+type fakeDevice struct {
+	p *fakePeripheral
+}
+
+func (d *fakeDevice) DiscoverServices(uuids []bluetooth.UUID) ([]Service, error) {
+	if !d.p.isConnected() {
+		return nil, errNotConnected
+	}
+	if d.p.noService || !slices.Contains(uuids, serviceUUID) {
+		return nil, nil
+	}
+	return []Service{&fakeService{p: d.p}}, nil
+}
+
+func (d *fakeDevice) Disconnect() error {
+	d.p.Drop()
+	return nil
+}
+
+type fakeService struct {
+	p *fakePeripheral
+}
+
+func (s *fakeService) DiscoverCharacteristics(uuids []bluetooth.UUID) ([]Characteristic, error) {
+	var characteristics []Characteristic
+	// The characteristics are returned in the opposite order to the UUIDs
+	// asked for, which real stacks do not promise to keep either.
+	for _, uuid := range []bluetooth.UUID{frDeviceUUID, toDeviceUUID} {
+		if slices.Contains(uuids, uuid) {
+			characteristics = append(characteristics, &fakeCharacteristic{p: s.p, uuid: uuid})
+		}
+	}
+	return characteristics, nil
+}
+
+type fakeCharacteristic struct {
+	p    *fakePeripheral
+	uuid bluetooth.UUID
+}
+
+func (c *fakeCharacteristic) UUID() bluetooth.UUID {
+	return c.uuid
+}
+
+func (c *fakeCharacteristic) Write(p []byte) (int, error) {
+	if c.uuid != toDeviceUUID {
+		return 0, errors.New("characteristic is not writable")
+	}
+	if !c.p.isConnected() {
+		return 0, errNotConnected
+	}
+	c.p.frames <- slices.Clone(p)
+	return len(p), nil
+}
+
+func (c *fakeCharacteristic) GetMTU() (uint16, error) {
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+	return c.p.mtu, nil
+}
+
+func (c *fakeCharacteristic) EnableNotifications(callback func(data []byte)) error {
+	if c.uuid != frDeviceUUID {
+		return errors.New("characteristic does not notify")
+	}
+
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+	if !c.p.connected {
+		return errNotConnected
+	}
+	c.p.notify = callback
+	return nil
+}
@@ -1,22 +1,69 @@
 package bluetooth
 
-import "github.com/kellegous/meshcore"
+import (
+	"log/slog"
+	"time"
+
+	"github.com/kellegous/meshcore"
+)
+
+// maxReconnectDelay caps the backoff between reconnect attempts.
+const maxReconnectDelay = time.Minute
 
 type ConnectOptions struct {
-	onRecv func(code meshcore.NotificationCode, data []byte)
-	onSend func(code meshcore.CommandCode, data []byte)
+	onStateChange  func(ConnectionState)
+	reconnectDelay time.Duration
+	middleware     []meshcore.Middleware
+	log            *meshcore.LogOptions
+}
+
+// logger returns the logger for transport events, which discards them if
+// logging is not enabled.
+func (o *ConnectOptions) logger() *slog.Logger {
+	switch {
+	case o.log == nil:
+		return slog.New(slog.DiscardHandler)
+	case o.log.Logger != nil:
+		return o.log.Logger
+	}
+	return slog.Default()
 }
 
 type ConnectOption func(*ConnectOptions)
 
-func OnRecv(fn func(code meshcore.NotificationCode, data []byte)) ConnectOption {
+// OnStateChange calls fn when the link to the device changes state. It must
+// not block.
+func OnStateChange(fn func(ConnectionState)) ConnectOption {
+	return func(opts *ConnectOptions) {
+		opts.onStateChange = fn
+	}
+}
+
+// AutoReconnect reconnects to the same address when the link drops. It waits
+// delay before the first attempt and doubles the wait after each failure, up
+// to a minute. The device forgets the session when the link drops, so use
+// OnStateChange to redo the app start once it is StateConnected again.
+func AutoReconnect(delay time.Duration) ConnectOption {
 	return func(opts *ConnectOptions) {
-		opts.onRecv = fn
+		opts.reconnectDelay = delay
 	}
 }
 
-func OnSend(fn func(code meshcore.CommandCode, data []byte)) ConnectOption {
+// Middleware passes the connection's frames through mws. See
+// meshcore.WithMiddleware for how they are ordered.
+func Middleware(mws ...meshcore.Middleware) ConnectOption {
 	return func(opts *ConnectOptions) {
-		opts.onSend = fn
+		opts.middleware = append(opts.middleware, mws...)
+	}
+}
+
+// Logging logs the connection's commands and notifications, along with
+// transport events like connects, disconnects and errors.
+func Logging(opts *meshcore.LogOptions) ConnectOption {
+	return func(o *ConnectOptions) {
+		if opts == nil {
+			opts = &meshcore.LogOptions{}
+		}
+		o.log = opts
 	}
 }
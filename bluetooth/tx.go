@@ -1,28 +1,231 @@
 package bluetooth
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/kellegous/poop"
 	"tinygo.org/x/bluetooth"
 
 	"github.com/kellegous/meshcore"
 )
 
+// ErrNotConnected is returned by writes while the link to the device is
+// down.
+var ErrNotConnected = errors.New("bluetooth device is not connected")
+
+// attHeaderSize is the part of the MTU taken by the ATT header of a write.
+const attHeaderSize = 3
+
+// ConnectionState is the state of the link to a device.
+type ConnectionState int
+
+const (
+	StateConnected ConnectionState = iota
+	StateDisconnected
+	StateReconnecting
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	case StateReconnecting:
+		return "reconnecting"
+	}
+	return fmt.Sprintf("ConnectionState(%d)", int(s))
+}
+
 type tx struct {
-	device   bluetooth.Device
-	toDevice bluetooth.DeviceCharacteristic
+	client  *Client
+	address bluetooth.Address
 	*meshcore.NotificationCenter
-	opts *ConnectOptions
+	options *ConnectOptions
+	logger  *slog.Logger
+
+	mu       sync.Mutex
+	device   Device
+	toDevice Characteristic
+	// maxWrite is the most that fits in one write, or 0 if the MTU is
+	// unknown.
+	maxWrite int
+	state    ConnectionState
+	closed   bool
+	stop     chan struct{}
 }
 
 var _ meshcore.Transport = (*tx)(nil)
 
-func (t *tx) Write(p []byte) (n int, err error) {
-	if nf := t.opts.onSend; nf != nil && len(p) > 0 {
-		nf(meshcore.CommandCode(p[0]), p[1:])
+// connect connects to the device and starts receiving its notifications.
+func (t *tx) connect() error {
+	device, err := t.client.adapter.Connect(t.address)
+	if err != nil {
+		t.logger.Error("bluetooth connect failed", slog.Any("error", err))
+		return err
+	}
+
+	toDevice, frDevice, err := discoverCharacteristics(device)
+	if err != nil {
+		t.logger.Error("bluetooth discovery failed", slog.Any("error", err))
+		device.Disconnect()
+		return err
+	}
+
+	if err := frDevice.EnableNotifications(t.notify); err != nil {
+		t.logger.Error("bluetooth enable notifications failed", slog.Any("error", err))
+		device.Disconnect()
+		return err
 	}
 
-	return t.toDevice.Write(p)
+	maxWrite := maxWriteSize(toDevice)
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		device.Disconnect()
+		return ErrNotConnected
+	}
+	t.device = device
+	t.toDevice = toDevice
+	t.maxWrite = maxWrite
+	t.state = StateConnected
+	t.mu.Unlock()
+
+	t.logger.Info("bluetooth connected", slog.Int("max_write", maxWrite))
+	t.setState(StateConnected)
+	return nil
+}
+
+func (t *tx) notify(data []byte) {
+	if len(data) == 0 {
+		t.logger.Warn("bluetooth notification empty")
+		return
+	}
+	t.Publish(meshcore.NotificationCode(data[0]), data[1:])
+}
+
+func (t *tx) setState(state ConnectionState) {
+	if fn := t.options.onStateChange; fn != nil {
+		fn(state)
+	}
+}
+
+// lost handles the link dropping without Disconnect being called.
+func (t *tx) lost() {
+	t.mu.Lock()
+	if t.closed || t.state != StateConnected {
+		t.mu.Unlock()
+		return
+	}
+	t.state = StateDisconnected
+	t.mu.Unlock()
+
+	t.logger.Warn("bluetooth link lost")
+	// Nothing waiting on the old link will be answered.
+	t.Shutdown()
+	t.setState(StateDisconnected)
+
+	if t.options.reconnectDelay > 0 {
+		go t.reconnect()
+	}
+}
+
+func (t *tx) reconnect() {
+	delay := t.options.reconnectDelay
+	for {
+		t.mu.Lock()
+		if t.closed {
+			t.mu.Unlock()
+			return
+		}
+		t.state = StateReconnecting
+		t.mu.Unlock()
+		t.setState(StateReconnecting)
+
+		select {
+		case <-time.After(delay):
+		case <-t.stop:
+			return
+		}
+
+		if err := t.connect(); err == nil {
+			return
+		}
+		delay = min(2*delay, maxReconnectDelay)
+	}
+}
+
+// maxWriteSize returns the most that fits in one write to c, or 0 if its MTU
+// is unknown.
+func maxWriteSize(c Characteristic) int {
+	mtu, err := c.GetMTU()
+	if err != nil || mtu <= attHeaderSize {
+		return 0
+	}
+	return int(mtu) - attHeaderSize
+}
+
+// refreshMaxWrite reads the MTU again, since it may have been negotiated
+// after connecting.
+func (t *tx) refreshMaxWrite(toDevice Characteristic) int {
+	maxWrite := maxWriteSize(toDevice)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.toDevice == toDevice {
+		t.maxWrite = maxWrite
+	}
+	return maxWrite
+}
+
+func (t *tx) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	toDevice, maxWrite, state := t.toDevice, t.maxWrite, t.state
+	t.mu.Unlock()
+
+	if state != StateConnected {
+		return 0, ErrNotConnected
+	}
+
+	// The MTU may have been negotiated after connecting, so read it again
+	// before giving up. NUS takes each write as a whole frame, so a frame
+	// that still does not fit cannot be split.
+	if maxWrite > 0 && len(p) > maxWrite {
+		maxWrite = t.refreshMaxWrite(toDevice)
+	}
+	if maxWrite > 0 && len(p) > maxWrite {
+		return 0, poop.Newf("frame of %d bytes exceeds the MTU, which allows %d", len(p), maxWrite)
+	}
+
+	n, err := toDevice.Write(p)
+	if err != nil {
+		t.logger.Error("bluetooth write failed", slog.Any("error", err))
+	}
+	return n, err
 }
 
 func (t *tx) Disconnect() error {
-	return t.device.Disconnect()
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	close(t.stop)
+	device, state := t.device, t.state
+	t.state = StateDisconnected
+	t.mu.Unlock()
+
+	t.client.unregister(t)
+	t.logger.Info("bluetooth disconnected")
+	t.setState(StateDisconnected)
+	if state != StateConnected {
+		return nil
+	}
+	return device.Disconnect()
 }
@@ -0,0 +1,171 @@
+package meshcore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+
+	"github.com/kellegous/poop"
+)
+
+const (
+	channelSecretSize = 16
+
+	publicChannelName = "Public"
+
+	channelURIPrefix = "meshcore://channel/add?"
+)
+
+// publicChannelSecret is the well-known secret of the public channel that
+// devices are configured with by default.
+var publicChannelSecret = []byte{
+	0x8b, 0x33, 0x87, 0xe9, 0xc5, 0xcd, 0xea, 0x6a,
+	0xc9, 0xe5, 0xed, 0xba, 0xa1, 0x15, 0xcd, 0x72,
+}
+
+// PublicChannel returns the well-known public channel.
+func PublicChannel() *ChannelInfo {
+	return &ChannelInfo{
+		Name:   publicChannelName,
+		Secret: bytes.Clone(publicChannelSecret),
+	}
+}
+
+// HashtagChannel returns the channel for the given hashtag. The secret of a
+// hashtag channel is derived from its name, so anyone who knows the name can
+// join. The leading # is added if it is missing.
+func HashtagChannel(name string) (*ChannelInfo, error) {
+	if !strings.HasPrefix(name, "#") {
+		name = "#" + name
+	}
+	if len(name) < 2 {
+		return nil, poop.New("hashtag channel name is empty")
+	}
+
+	h := sha256.Sum256([]byte(name))
+	return &ChannelInfo{
+		Name:   name,
+		Secret: h[:channelSecretSize],
+	}, nil
+}
+
+// IsEmpty reports whether the channel slot is unused.
+func (c *ChannelInfo) IsEmpty() bool {
+	return c.Name == "" && bytes.Count(c.Secret, []byte{0}) == len(c.Secret)
+}
+
+// URI returns a meshcore:// link that can be used to share the channel.
+//
+//	meshcore://channel/add?name=<name>&secret=<hex secret>
+func (c *ChannelInfo) URI() string {
+	values := url.Values{}
+	values.Set("name", c.Name)
+	values.Set("secret", hex.EncodeToString(c.Secret))
+	return channelURIPrefix + values.Encode()
+}
+
+// ParseChannelURI parses a channel shared as a meshcore:// link. The Index
+// of the returned channel is always 0.
+func ParseChannelURI(s string) (*ChannelInfo, error) {
+	if len(s) < len(channelURIPrefix) || !strings.EqualFold(s[:len(channelURIPrefix)], channelURIPrefix) {
+		return nil, poop.Newf("channel uri must start with %s", channelURIPrefix)
+	}
+
+	values, err := url.ParseQuery(s[len(channelURIPrefix):])
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	secret, err := hex.DecodeString(values.Get("secret"))
+	if err != nil {
+		return nil, poop.Chain(err)
+	} else if len(secret) != channelSecretSize {
+		return nil, poop.Newf("secret must be %d bytes, got %d", channelSecretSize, len(secret))
+	}
+
+	name := values.Get("name")
+	if name == "" {
+		return nil, poop.New("channel name is empty")
+	}
+
+	return &ChannelInfo{
+		Name:   name,
+		Secret: secret,
+	}, nil
+}
+
+// MaxChannels returns the number of channel slots the device supports.
+func (c *Conn) MaxChannels(ctx context.Context) (int, error) {
+	channels, err := c.GetChannels(ctx)
+	if err != nil {
+		return 0, poop.Chain(err)
+	}
+	return len(channels), nil
+}
+
+// FindChannel returns the channel with the given name, or nil if the device
+// has no channel with that name.
+func (c *Conn) FindChannel(ctx context.Context, name string) (*ChannelInfo, error) {
+	channels, err := c.GetChannels(ctx)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	for _, channel := range channels {
+		if !channel.IsEmpty() && channel.Name == name {
+			return channel, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// AddChannel adds the channel to the first free slot on the device and
+// returns it with its Index set. The Index of the given channel is ignored.
+// An error is returned if the device already has a channel with the same
+// name or secret, or if all of the device's slots are in use.
+func (c *Conn) AddChannel(ctx context.Context, channel *ChannelInfo) (*ChannelInfo, error) {
+	if len(channel.Secret) != channelSecretSize {
+		return nil, poop.Newf("secret must be %d bytes, got %d", channelSecretSize, len(channel.Secret))
+	}
+
+	channels, err := c.GetChannels(ctx)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	var free *ChannelInfo
+	for _, existing := range channels {
+		if existing.IsEmpty() {
+			if free == nil {
+				free = existing
+			}
+			continue
+		}
+
+		if existing.Name == channel.Name {
+			return nil, poop.Newf("a channel named %q already exists in slot %d", channel.Name, existing.Index)
+		}
+		if bytes.Equal(existing.Secret, channel.Secret) {
+			return nil, poop.Newf("channel %q already exists in slot %d", existing.Name, existing.Index)
+		}
+	}
+
+	if free == nil {
+		return nil, poop.Newf("all %d channel slots are in use", len(channels))
+	}
+
+	added := &ChannelInfo{
+		Index:  free.Index,
+		Name:   channel.Name,
+		Secret: bytes.Clone(channel.Secret),
+	}
+	if err := c.SetChannel(ctx, added); err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	return added, nil
+}
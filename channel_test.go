@@ -0,0 +1,201 @@
+package meshcore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// serveChannels answers GetChannel commands from the given table until the
+// device reports that the index is out of range.
+func serveChannels(t *testing.T, controller *Controller, channels []*ChannelInfo) {
+	for i := 0; ; i++ {
+		if err := ValidateBytes(
+			controller.Recv(),
+			Command(CommandGetChannel),
+			Byte(byte(i)),
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		if i >= len(channels) {
+			controller.Notify(NotificationTypeErr, BytesFrom(Byte(byte(ErrorCodeNotFound))))
+			return
+		}
+
+		channel := channels[i]
+		controller.Notify(NotificationTypeChannelInfo, BytesFrom(
+			Byte(channel.Index),
+			CString(channel.Name, 32),
+			Bytes(channel.Secret...),
+		))
+	}
+}
+
+func emptyChannels(n int) []*ChannelInfo {
+	channels := make([]*ChannelInfo, n)
+	for i := range channels {
+		channels[i] = &ChannelInfo{
+			Index:  uint8(i),
+			Secret: make([]byte, 16),
+		}
+	}
+	return channels
+}
+
+func TestPublicChannel(t *testing.T) {
+	channel := PublicChannel()
+	if channel.Name != "Public" {
+		t.Fatalf("expected name Public, got %s", channel.Name)
+	}
+	if hex.EncodeToString(channel.Secret) != "8b3387e9c5cdea6ac9e5edbaa115cd72" {
+		t.Fatalf("unexpected secret: %x", channel.Secret)
+	}
+}
+
+func TestHashtagChannel(t *testing.T) {
+	for _, name := range []string{"test", "#test"} {
+		channel, err := HashtagChannel(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if channel.Name != "#test" {
+			t.Fatalf("expected name #test, got %s", channel.Name)
+		}
+		if hex.EncodeToString(channel.Secret) != "9cd8fcf22a47333b591d96a2b848b73f" {
+			t.Fatalf("unexpected secret: %x", channel.Secret)
+		}
+	}
+
+	if _, err := HashtagChannel("#"); err == nil {
+		t.Fatal("expected error for empty name")
+	}
+}
+
+func TestChannelURI(t *testing.T) {
+	channel, err := HashtagChannel("hello world")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uri := channel.URI()
+	parsed, err := ParseChannelURI(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Name != channel.Name || !bytes.Equal(parsed.Secret, channel.Secret) {
+		t.Fatalf("expected %s, got %s", describe(channel), describe(parsed))
+	}
+
+	for _, tc := range []struct {
+		uri string
+		err string
+	}{
+		{"https://example.com", "channel uri must start with meshcore://channel/add?"},
+		{"meshcore://channel/add?name=a&secret=00", "secret must be 16 bytes, got 1"},
+		{"meshcore://channel/add?secret=8b3387e9c5cdea6ac9e5edbaa115cd72", "channel name is empty"},
+	} {
+		if _, err := ParseChannelURI(tc.uri); err == nil || err.Error() != tc.err {
+			t.Fatalf("expected error %q for %s, got %v", tc.err, tc.uri, err)
+		}
+	}
+}
+
+func TestAddChannel(t *testing.T) {
+	t.Run("free slot", func(t *testing.T) {
+		channels := emptyChannels(4)
+		channels[0].Name = "Public"
+		channels[0].Secret = PublicChannel().Secret
+
+		hashtag, err := HashtagChannel("test")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		controller := DoCommand(func(conn *Conn) {
+			added, err := conn.AddChannel(t.Context(), hashtag)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if added.Index != 1 {
+				t.Fatalf("expected index 1, got %d", added.Index)
+			}
+		})
+
+		serveChannels(t, controller, channels)
+
+		if err := ValidateBytes(
+			controller.Recv(),
+			Command(CommandSetChannel),
+			Byte(1),
+			CString(hashtag.Name, 32),
+			Bytes(hashtag.Secret...),
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		controller.Notify(NotificationTypeOk, nil)
+
+		controller.Wait()
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		channels := emptyChannels(2)
+		channels[1].Name = "Public"
+		channels[1].Secret = PublicChannel().Secret
+
+		controller := DoCommand(func(conn *Conn) {
+			channel := PublicChannel()
+			channel.Name = "Other"
+			if _, err := conn.AddChannel(t.Context(), channel); err == nil || err.Error() != `channel "Public" already exists in slot 1` {
+				t.Fatalf("expected duplicate error, got %v", err)
+			}
+		})
+
+		serveChannels(t, controller, channels)
+
+		controller.Wait()
+	})
+
+	t.Run("full", func(t *testing.T) {
+		channels := emptyChannels(1)
+		channels[0].Name = "Public"
+		channels[0].Secret = PublicChannel().Secret
+
+		controller := DoCommand(func(conn *Conn) {
+			channel, err := HashtagChannel("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := conn.AddChannel(t.Context(), channel); err == nil || err.Error() != "all 1 channel slots are in use" {
+				t.Fatalf("expected full error, got %v", err)
+			}
+		})
+
+		serveChannels(t, controller, channels)
+
+		controller.Wait()
+	})
+}
+
+func TestFindChannel(t *testing.T) {
+	channels := emptyChannels(3)
+	channels[2].Name = "#test"
+	channels[2].Secret = fakeBytes(16, func(i int) byte {
+		return byte(i + 1)
+	})
+
+	controller := DoCommand(func(conn *Conn) {
+		channel, err := conn.FindChannel(t.Context(), "#test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if channel == nil || channel.Index != 2 {
+			t.Fatalf("expected channel in slot 2, got %s", describe(channel))
+		}
+	})
+
+	serveChannels(t, controller, channels)
+
+	controller.Wait()
+}
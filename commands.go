@@ -32,7 +32,7 @@ const (
 	CommandImportContact     CommandCode = 18
 	CommandReboot            CommandCode = 19
 	CommandGetBatteryVoltage CommandCode = 20
-	CommandSetTuningParams   CommandCode = 21 // todo
+	CommandSetTuningParams   CommandCode = 21
 	CommandDeviceQuery       CommandCode = 22
 	CommandExportPrivateKey  CommandCode = 23
 	CommandImportPrivateKey  CommandCode = 24
@@ -47,6 +47,7 @@ const (
 	CommandSendTracePath     CommandCode = 36
 	CommandSetOtherParams    CommandCode = 38
 	CommandSendTelemetryReq  CommandCode = 39
+	CommandGetTuningParams   CommandCode = 43
 	CommandSendBinaryReq     CommandCode = 50
 )
 
@@ -86,6 +87,7 @@ var commandCodeText = map[CommandCode]string{
 	CommandSendTracePath:     "SendTracePath",
 	CommandSetOtherParams:    "SetOtherParams",
 	CommandSendTelemetryReq:  "SendTelemetryReq",
+	CommandGetTuningParams:   "GetTuningParams",
 	CommandSendBinaryReq:     "SendBinaryReq",
 }
 
@@ -93,6 +95,44 @@ func (c CommandCode) String() string {
 	return commandCodeText[c]
 }
 
+type pendingCommand struct {
+	code CommandCode
+	at   time.Time
+}
+
+// pendingCommands are commands that have been sent and are waiting for a
+// response. The device answers commands in order, so the oldest pending
+// command is the one being answered by the next response.
+type pendingCommands struct {
+	commands []pendingCommand
+}
+
+// maxPendingCommands bounds the commands waiting for a response, so that
+// commands whose responses are lost do not accumulate.
+const maxPendingCommands = 64
+
+func (p *pendingCommands) push(code CommandCode, at time.Time) {
+	if len(p.commands) == maxPendingCommands {
+		p.commands = p.commands[1:]
+	}
+	p.commands = append(p.commands, pendingCommand{code: code, at: at})
+}
+
+// pop removes the oldest pending command if code is a response.
+func (p *pendingCommands) pop(code NotificationCode) (pendingCommand, bool) {
+	// Contacts after the first are part of the same response.
+	if code >= NotificationTypeAdvert ||
+		code == NotificationTypeContact ||
+		code == NotificationTypeEndOfContacts ||
+		len(p.commands) == 0 {
+		return pendingCommand{}, false
+	}
+
+	c := p.commands[0]
+	p.commands = p.commands[1:]
+	return c, true
+}
+
 type TextType byte
 
 const (
@@ -532,6 +572,21 @@ func writeSetRadioParamsCommand(w io.Writer, radioFreq uint32, radioBw uint32, r
 	return nil
 }
 
+func writeSetTuningParamsCommand(w io.Writer, params *TuningParams) error {
+	var buf bytes.Buffer
+	if err := writeCommandCode(&buf, CommandSetTuningParams); err != nil {
+		return poop.Chain(err)
+	}
+	if err := params.writeTo(&buf); err != nil {
+		return poop.Chain(err)
+	}
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return poop.Chain(err)
+	}
+	return nil
+}
+
 func writeSendBinaryRequestCommand(w io.Writer, recipient PublicKey, payload []byte) error {
 	var buf bytes.Buffer
 	if err := writeCommandCode(&buf, CommandSendBinaryReq); err != nil {
@@ -550,6 +605,34 @@ func writeSendBinaryRequestCommand(w io.Writer, recipient PublicKey, payload []b
 	return nil
 }
 
+func writeSendRawDataCommand(w io.Writer, path []byte, payload []byte) error {
+	if len(path) > 64 {
+		return poop.Newf("path is %d bytes, max is 64", len(path))
+	}
+	if len(payload) < 4 {
+		return poop.New("raw data must be at least 4 bytes")
+	}
+
+	var buf bytes.Buffer
+	if err := writeCommandCode(&buf, CommandSendRawData); err != nil {
+		return poop.Chain(err)
+	}
+	if err := buf.WriteByte(byte(len(path))); err != nil {
+		return poop.Chain(err)
+	}
+	if _, err := buf.Write(path); err != nil {
+		return poop.Chain(err)
+	}
+	if _, err := buf.Write(payload); err != nil {
+		return poop.Chain(err)
+	}
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return poop.Chain(err)
+	}
+	return nil
+}
+
 func writeSetTXPowerCommand(w io.Writer, power byte) error {
 	var buf bytes.Buffer
 	if err := writeCommandCode(&buf, CommandSetTxPower); err != nil {
@@ -0,0 +1,292 @@
+package meshcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kellegous/poop"
+)
+
+// Config is the desired configuration of a device, typically kept in a JSON
+// file:
+//
+//	{
+//	  "name": "base-01",
+//	  "location": {"lat": 37.7749, "lon": -122.4194},
+//	  "tx_power": 20,
+//	  "radio_preset": "USA/Canada (Recommended)",
+//	  "manual_add_contacts": false,
+//	  "channels": [
+//	    {"name": "Public"},
+//	    {"name": "#ops"},
+//	    {"name": "private", "secret": "00112233445566778899aabbccddeeff"}
+//	  ]
+//	}
+//
+// Settings that are omitted are left unchanged on the device.
+type Config struct {
+	Name     *string      `json:"name,omitempty"`
+	Location *Location    `json:"location,omitempty"`
+	TxPower  *byte        `json:"tx_power,omitempty"`
+	Radio    *RadioParams `json:"radio,omitempty"`
+	// RadioPreset names one of the RadioPresets to use instead of Radio.
+	RadioPreset       string `json:"radio_preset,omitempty"`
+	ManualAddContacts *bool  `json:"manual_add_contacts,omitempty"`
+	// Channels is the complete set of channels, in slot order. Slots after
+	// the last channel are cleared. If nil, the device's channels are left
+	// unchanged; use an empty list to clear all of them.
+	Channels []ChannelConfig `json:"channels,omitempty"`
+}
+
+type Location struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%f,%f", l.Lat, l.Lon)
+}
+
+// sameAs reports whether l and o are the same once stored on the device.
+func (l Location) sameAs(o Location) bool {
+	return microDegrees(l.Lat) == microDegrees(o.Lat) &&
+		microDegrees(l.Lon) == microDegrees(o.Lon)
+}
+
+// ChannelConfig is a channel in a Config.
+type ChannelConfig struct {
+	Name string `json:"name"`
+	// Secret is the hex encoded channel secret. It may be omitted for the
+	// public channel and for hashtag channels, whose secrets are derived
+	// from their names.
+	Secret string `json:"secret,omitempty"`
+}
+
+// channel returns the channel for slot idx.
+func (c *ChannelConfig) channel(idx int) (*ChannelInfo, error) {
+	var channel *ChannelInfo
+	switch {
+	case c.Secret != "":
+		secret, err := hex.DecodeString(c.Secret)
+		if err != nil {
+			return nil, poop.Chain(err)
+		} else if len(secret) != channelSecretSize {
+			return nil, poop.Newf("secret must be %d bytes, got %d", channelSecretSize, len(secret))
+		}
+		channel = &ChannelInfo{Name: c.Name, Secret: secret}
+	case strings.HasPrefix(c.Name, "#"):
+		var err error
+		channel, err = HashtagChannel(c.Name)
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+	case c.Name == publicChannelName:
+		channel = PublicChannel()
+	default:
+		return nil, poop.Newf("channel %q needs a secret", c.Name)
+	}
+
+	channel.Index = uint8(idx)
+	return channel, nil
+}
+
+// ReadConfig reads a JSON encoded Config. Unknown fields are rejected so
+// that typos do not go unnoticed.
+func ReadConfig(r io.Reader) (*Config, error) {
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+
+	var c Config
+	if err := d.Decode(&c); err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	return &c, nil
+}
+
+// Validate checks the parts of the config that can be checked without a
+// device.
+func (c *Config) Validate() error {
+	if c.Name != nil && (*c.Name == "" || len(*c.Name) > 31) {
+		return poop.Newf("name must be 1 to 31 bytes, got %d", len(*c.Name))
+	}
+
+	if _, err := c.radio(); err != nil {
+		return poop.Chain(err)
+	}
+
+	names := map[string]bool{}
+	for i := range c.Channels {
+		channel, err := c.Channels[i].channel(i)
+		if err != nil {
+			return poop.Chain(err)
+		}
+		if len(channel.Name) > 31 {
+			return poop.Newf("channel name %q is longer than 31 bytes", channel.Name)
+		}
+		if names[channel.Name] {
+			return poop.Newf("channel %q is listed more than once", channel.Name)
+		}
+		names[channel.Name] = true
+	}
+
+	return nil
+}
+
+// Plan reads the device's current configuration and returns the changes
+// needed to make it match the config.
+func (c *Config) Plan(ctx context.Context, conn *Conn) (*Plan, error) {
+	if err := c.Validate(); err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	info, err := conn.GetSelfInfo(ctx)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	var p Plan
+	if err := c.planSelf(&p, info); err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	if c.Channels == nil {
+		return &p, nil
+	}
+
+	channels, err := conn.GetChannels(ctx)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	if len(c.Channels) > len(channels) {
+		return nil, poop.Newf("config has %d channels but the device only has %d slots", len(c.Channels), len(channels))
+	}
+
+	for i, have := range channels {
+		want := &ChannelInfo{
+			Index:  uint8(i),
+			Secret: make([]byte, channelSecretSize),
+		}
+		if i < len(c.Channels) {
+			want, err = c.Channels[i].channel(i)
+			if err != nil {
+				return nil, poop.Chain(err)
+			}
+		}
+		planChannel(&p, have, want)
+	}
+
+	return &p, nil
+}
+
+// Apply changes the device to match the config and returns the changes that
+// were made.
+func (c *Config) Apply(ctx context.Context, conn *Conn) (*Plan, error) {
+	p, err := c.Plan(ctx, conn)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	if err := p.Apply(ctx, conn); err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	return p, nil
+}
+
+// radio returns the radio parameters given by either Radio or RadioPreset,
+// or nil if neither is set.
+func (c *Config) radio() (*RadioParams, error) {
+	switch {
+	case c.Radio != nil && c.RadioPreset != "":
+		return nil, poop.New("radio and radio_preset cannot both be set")
+	case c.RadioPreset != "":
+		preset, err := FindRadioPreset(c.RadioPreset)
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+		return &preset.Params, nil
+	case c.Radio != nil:
+		if err := c.Radio.Validate(); err != nil {
+			return nil, poop.Chain(err)
+		}
+		return c.Radio, nil
+	}
+	return nil, nil
+}
+
+func (c *Config) planSelf(p *Plan, current *SelfInfo) error {
+	if name := c.Name; name != nil && *name != current.Name {
+		p.add(func(ctx context.Context, conn *Conn) error {
+			return conn.SetAdvertName(ctx, *name)
+		}, "name: %q -> %q", current.Name, *name)
+	}
+
+	have := Location{Lat: current.AdvLat, Lon: current.AdvLon}
+	if loc := c.Location; loc != nil && !loc.sameAs(have) {
+		p.add(func(ctx context.Context, conn *Conn) error {
+			return conn.SetAdvertLatLon(ctx, loc.Lat, loc.Lon)
+		}, "location: %s -> %s", have, loc)
+	}
+
+	if power := c.TxPower; power != nil && *power != current.TxPower {
+		if err := current.ValidateTxPower(*power); err != nil {
+			return poop.Chain(err)
+		}
+		p.add(func(ctx context.Context, conn *Conn) error {
+			return conn.SetTXPower(ctx, *power)
+		}, "tx power: %d dBm -> %d dBm", current.TxPower, *power)
+	}
+
+	params, err := c.radio()
+	if err != nil {
+		return poop.Chain(err)
+	}
+	if radio := current.RadioParams(); params != nil && *params != radio {
+		p.add(func(ctx context.Context, conn *Conn) error {
+			return conn.SetRadioParams(ctx, params)
+		}, "radio: %s -> %s", radio, params)
+	}
+
+	manual := current.ManualAddContacts != 0
+	if want := c.ManualAddContacts; want != nil && *want != manual {
+		p.add(func(ctx context.Context, conn *Conn) error {
+			return conn.SetOtherParams(ctx, *want)
+		}, "manual add contacts: %t -> %t", manual, *want)
+	}
+
+	return nil
+}
+
+func describeChannel(c *ChannelInfo) string {
+	if c.IsEmpty() {
+		return "(empty)"
+	}
+	return fmt.Sprintf("%q", c.Name)
+}
+
+// planChannel adds a change to set slot have.Index to want, if they differ.
+func planChannel(p *Plan, have, want *ChannelInfo) {
+	if have.Name == want.Name && bytes.Equal(have.Secret, want.Secret) {
+		return
+	}
+
+	from, to := describeChannel(have), describeChannel(want)
+	if from == to {
+		to += " (new secret)"
+	}
+
+	p.add(func(ctx context.Context, conn *Conn) error {
+		return conn.SetChannel(ctx, want)
+	}, "channel %d: %s -> %s", want.Index, from, to)
+}
@@ -0,0 +1,173 @@
+package meshcore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadConfig(t *testing.T) {
+	tests := []struct {
+		Name  string
+		JSON  string
+		Error string
+	}{
+		{
+			Name: "valid",
+			JSON: `{
+				"name": "base-01",
+				"tx_power": 20,
+				"channels": [{"name": "Public"}, {"name": "#ops"}]
+			}`,
+		},
+		{
+			Name:  "unknown field",
+			JSON:  `{"nmae": "base-01"}`,
+			Error: `json: unknown field "nmae"`,
+		},
+		{
+			Name:  "empty name",
+			JSON:  `{"name": ""}`,
+			Error: "name must be 1 to 31 bytes, got 0",
+		},
+		{
+			Name:  "channel without secret",
+			JSON:  `{"channels": [{"name": "private"}]}`,
+			Error: `channel "private" needs a secret`,
+		},
+		{
+			Name:  "short secret",
+			JSON:  `{"channels": [{"name": "private", "secret": "0011"}]}`,
+			Error: "secret must be 16 bytes, got 2",
+		},
+		{
+			Name:  "unknown radio preset",
+			JSON:  `{"radio_preset": "Atlantis"}`,
+			Error: `unknown radio preset: "Atlantis"`,
+		},
+		{
+			Name:  "radio and preset",
+			JSON:  `{"radio_preset": "Vietnam", "radio": {"freq": 920.25, "bw": 250, "sf": 11, "cr": 5}}`,
+			Error: "radio and radio_preset cannot both be set",
+		},
+		{
+			Name:  "invalid radio",
+			JSON:  `{"radio": {"freq": 920.25, "bw": 250, "sf": 13, "cr": 5}}`,
+			Error: "spreading factor must be 5 to 12, got 13",
+		},
+		{
+			Name:  "duplicate channel",
+			JSON:  `{"channels": [{"name": "#ops"}, {"name": "#ops"}]}`,
+			Error: `channel "#ops" is listed more than once`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := ReadConfig(strings.NewReader(test.JSON))
+			if test.Error == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.Error {
+				t.Fatalf("expected error %q, got %v", test.Error, err)
+			}
+		})
+	}
+}
+
+func TestConfigApply(t *testing.T) {
+	d, _ := configuredFakeDevice(t)
+
+	config, err := ReadConfig(strings.NewReader(`{
+		"name": "base-01",
+		"tx_power": 20,
+		"radio_preset": "EU/UK (Long Range)",
+		"channels": [{"name": "Public"}, {"name": "#ops"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(fn func(conn *Conn) (*Plan, error)) *Plan {
+		var plan *Plan
+		controller := DoCommand(func(conn *Conn) {
+			var err error
+			plan, err = fn(conn)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+		d.serve(t, controller)
+		controller.Wait()
+		return plan
+	}
+
+	plan := run(func(conn *Conn) (*Plan, error) {
+		return config.Plan(t.Context(), conn)
+	})
+	expected := `  name: "base" -> "base-01"
+  radio: 910.525 MHz, BW 62.5 kHz, SF7, CR5 -> 869.525 MHz, BW 250 kHz, SF11, CR5
+  channel 1: (empty) -> "#ops"
+  channel 2: "#test" -> (empty)
+`
+	if plan.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, plan)
+	}
+	if d.info.Name != "base" {
+		t.Fatal("plan changed the device")
+	}
+
+	run(func(conn *Conn) (*Plan, error) {
+		return config.Apply(t.Context(), conn)
+	})
+	if d.info.Name != "base-01" || d.info.RadioSf != 11 {
+		t.Fatalf("config was not applied: %s", describe(d.info))
+	}
+	if d.channels[1].Name != "#ops" || !d.channels[2].IsEmpty() {
+		t.Fatalf("channels were not applied: %s", describe(d.channels))
+	}
+
+	plan = run(func(conn *Conn) (*Plan, error) {
+		return config.Plan(t.Context(), conn)
+	})
+	if !plan.IsEmpty() || plan.String() != "no changes\n" {
+		t.Fatalf("expected no changes, got:\n%s", plan)
+	}
+
+	tooMany := &Config{Channels: make([]ChannelConfig, 5)}
+	for i := range tooMany.Channels {
+		tooMany.Channels[i].Name = "#" + strings.Repeat("x", i+1)
+	}
+	controller := DoCommand(func(conn *Conn) {
+		_, err := tooMany.Plan(t.Context(), conn)
+		if err == nil || err.Error() != "config has 5 channels but the device only has 4 slots" {
+			t.Errorf("expected too many channels error, got %v", err)
+		}
+	})
+	d.serve(t, controller)
+	controller.Wait()
+}
+
+func TestConfigPlanLocation(t *testing.T) {
+	current := &SelfInfo{AdvLat: 37.7749, AdvLon: -122.4194}
+
+	// The device only keeps six decimal places.
+	var p Plan
+	config := &Config{Location: &Location{Lat: 37.77490012, Lon: -122.41939996}}
+	if err := config.planSelf(&p, current); err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsEmpty() {
+		t.Fatalf("expected no changes, got:\n%s", &p)
+	}
+
+	config = &Config{Location: &Location{Lat: 37.7751, Lon: -122.4194}}
+	if err := config.planSelf(&p, current); err != nil {
+		t.Fatal(err)
+	}
+	if p.IsEmpty() {
+		t.Fatal("expected a location change")
+	}
+}
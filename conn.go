@@ -8,11 +8,16 @@ import (
 	"errors"
 	"io"
 	"iter"
+	"math"
 	"time"
 
 	"github.com/kellegous/poop"
 )
 
+// ErrPrivateKeyDisabled is returned when the device does not allow its
+// private key to be exported or imported.
+var ErrPrivateKeyDisabled = errors.New("private key is disabled")
+
 type Transport interface {
 	io.Writer
 	Disconnect() error
@@ -20,7 +25,8 @@ type Transport interface {
 }
 
 type Conn struct {
-	tx Transport
+	tx        Transport
+	dutyCycle *DutyCycle
 }
 
 func NewConnection(tx Transport) *Conn {
@@ -192,6 +198,12 @@ func (c *Conn) SendTextMessage(
 	message string,
 	textType TextType,
 ) (*SentNotification, error) {
+	reservation, err := c.reserveAirtime(textMessagePacketSize(message))
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	defer reservation.release()
+
 	next, done := iter.Pull2(
 		c.tx.Subscribe(ctx, NotificationTypeSent, NotificationTypeErr),
 	)
@@ -200,6 +212,7 @@ func (c *Conn) SendTextMessage(
 	if err := writeSendTextMessageCommand(c.tx, recipient, message, textType, 0, time.Now()); err != nil {
 		return nil, poop.Chain(err)
 	}
+	reservation.keep()
 
 	res, err, _ := next()
 	if err != nil {
@@ -223,6 +236,12 @@ func (c *Conn) SendChannelTextMessage(
 	message string,
 	textType TextType,
 ) error {
+	reservation, err := c.reserveAirtime(channelTextMessagePacketSize(message))
+	if err != nil {
+		return poop.Chain(err)
+	}
+	defer reservation.release()
+
 	next, done := iter.Pull2(
 		c.tx.Subscribe(ctx, NotificationTypeOk, NotificationTypeErr),
 	)
@@ -231,6 +250,7 @@ func (c *Conn) SendChannelTextMessage(
 	if err := writeSendChannelTextMessageCommand(c.tx, channelIndex, message, textType, time.Now()); err != nil {
 		return poop.Chain(err)
 	}
+	reservation.keep()
 
 	res, err, _ := next()
 	if err != nil {
@@ -252,6 +272,12 @@ func (c *Conn) GetTelemetry(
 	ctx context.Context,
 	key *PublicKey,
 ) (*Telemetry, error) {
+	reservation, err := c.reserveAirtime(requestPacketSize())
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	defer reservation.release()
+
 	next, done := iter.Pull2(
 		c.tx.Subscribe(ctx, NotificationTypeTelemetry, NotificationTypeErr),
 	)
@@ -260,6 +286,7 @@ func (c *Conn) GetTelemetry(
 	if err := writeGetTelemetryCommand(c.tx, key); err != nil {
 		return nil, poop.Chain(err)
 	}
+	reservation.keep()
 
 	res, err, _ := next()
 	if err != nil {
@@ -433,6 +460,12 @@ func (c *Conn) SyncNextMessage(ctx context.Context) (Message, error) {
 
 // SendAdvert sends an advert to the device.
 func (c *Conn) SendAdvert(ctx context.Context, advertType SelfAdvertType) error {
+	reservation, err := c.reserveAirtime(advertPacketSize())
+	if err != nil {
+		return poop.Chain(err)
+	}
+	defer reservation.release()
+
 	next, done := iter.Pull2(
 		c.tx.Subscribe(ctx, NotificationTypeOk, NotificationTypeErr),
 	)
@@ -441,6 +474,7 @@ func (c *Conn) SendAdvert(ctx context.Context, advertType SelfAdvertType) error
 	if err := writeSendAdvertCommand(c.tx, advertType); err != nil {
 		return poop.Chain(err)
 	}
+	reservation.keep()
 	res, err, _ := next()
 	if err != nil {
 		return poop.Chain(err)
@@ -509,6 +543,12 @@ func (c *Conn) ImportContact(ctx context.Context, advertPacket []byte) error {
 
 // ShareContact shares a contact with the device.
 func (c *Conn) ShareContact(ctx context.Context, key PublicKey) error {
+	reservation, err := c.reserveAirtime(advertPacketSize())
+	if err != nil {
+		return poop.Chain(err)
+	}
+	defer reservation.release()
+
 	next, done := iter.Pull2(
 		c.tx.Subscribe(ctx, NotificationTypeOk, NotificationTypeErr),
 	)
@@ -517,6 +557,7 @@ func (c *Conn) ShareContact(ctx context.Context, key PublicKey) error {
 	if err := writeShareContactCommand(c.tx, &key); err != nil {
 		return poop.Chain(err)
 	}
+	reservation.keep()
 	res, err, _ := next()
 	if err != nil {
 		return poop.Chain(err)
@@ -552,7 +593,7 @@ func (c *Conn) ExportPrivateKey(ctx context.Context) ([]byte, error) {
 	case *PrivateKeyNotification:
 		return t.PrivateKey[:], nil
 	case *DisabledNotification:
-		return nil, poop.New("private key is disabled")
+		return nil, poop.Chain(ErrPrivateKeyDisabled)
 	case *ErrNotification:
 		return nil, poop.Chain(t.Error())
 	}
@@ -580,7 +621,7 @@ func (c *Conn) ImportPrivateKey(ctx context.Context, privateKey []byte) error {
 	case *OkNotification:
 		return nil
 	case *DisabledNotification:
-		return poop.New("private key is disabled")
+		return poop.Chain(ErrPrivateKeyDisabled)
 	case *ErrNotification:
 		return poop.Chain(t.Error())
 	}
@@ -592,6 +633,12 @@ func (c *Conn) ImportPrivateKey(ctx context.Context, privateKey []byte) error {
 func (c *Conn) GetStatus(ctx context.Context, key PublicKey) (*Status, error) {
 	// TODO(kellegous): This is not working on real devices currently. We seed the
 	// SentResponse arrive, but we never get a PushStatusResponse.
+	reservation, err := c.reserveAirtime(requestPacketSize())
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	defer reservation.release()
+
 	next, done := iter.Pull2(
 		c.tx.Subscribe(ctx, NotificationTypeStatus, NotificationTypeErr),
 	)
@@ -600,6 +647,7 @@ func (c *Conn) GetStatus(ctx context.Context, key PublicKey) (*Status, error) {
 	if err := writeGetStatusCommand(c.tx, &key); err != nil {
 		return nil, poop.Chain(err)
 	}
+	reservation.keep()
 
 	res, err, _ := next()
 	if err != nil {
@@ -744,6 +792,13 @@ func (c *Conn) GetSelfInfo(ctx context.Context) (*SelfInfo, error) {
 
 // Sign signs the given data.
 func (c *Conn) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	return c.SignReader(ctx, bytes.NewReader(data))
+}
+
+// SignReader signs the data read from r, streaming it to the device in
+// chunks. The device limits the amount of data it will sign, so an error is
+// returned if r produces more than the device's MaxSignDataLen.
+func (c *Conn) SignReader(ctx context.Context, r io.Reader) ([]byte, error) {
 	// In the normal case, this looks like:
 	// -> SignStartCommand
 	// <- SignStartNotification
@@ -754,7 +809,6 @@ func (c *Conn) Sign(ctx context.Context, data []byte) ([]byte, error) {
 	// -> SignFinishCommand
 	// <- SignatureNotification
 	const chunkSize = 128
-	buf := bytes.NewReader(data)
 
 	next, done := iter.Pull2(
 		c.tx.Subscribe(ctx,
@@ -770,13 +824,18 @@ func (c *Conn) Sign(ctx context.Context, data []byte) ([]byte, error) {
 		return nil, poop.Chain(err)
 	}
 
-	sendNextChunk := func() error {
+	var maxLen, sent int
+	readNextChunk := func() ([]byte, error) {
 		var chunk [chunkSize]byte
-		n, err := io.ReadFull(buf, chunk[:])
-		if err != nil && err != io.ErrUnexpectedEOF {
-			return poop.Chain(err)
+		n, err := io.ReadFull(r, chunk[:])
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return nil, poop.Chain(err)
+		}
+		sent += n
+		if sent > maxLen {
+			return nil, poop.New("data is too long")
 		}
-		return writeSignDataCommand(c.tx, chunk[:n])
+		return chunk[:n], nil
 	}
 
 	res, err, _ := next()
@@ -786,10 +845,16 @@ func (c *Conn) Sign(ctx context.Context, data []byte) ([]byte, error) {
 
 	switch t := res.(type) {
 	case *SignStartNotification:
-		if buf.Len() > int(t.MaxSignDataLen) {
+		maxLen = int(t.MaxSignDataLen)
+		// When the size is known up front, fail before sending anything.
+		if l, ok := r.(interface{ Len() int }); ok && l.Len() > maxLen {
 			return nil, poop.New("data is too long")
 		}
-		if err := sendNextChunk(); err != nil {
+		chunk, err := readNextChunk()
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+		if err := writeSignDataCommand(c.tx, chunk); err != nil {
 			return nil, poop.Chain(err)
 		}
 	case *ErrNotification:
@@ -810,8 +875,12 @@ func (c *Conn) Sign(ctx context.Context, data []byte) ([]byte, error) {
 		case *ErrNotification:
 			return nil, poop.Chain(t.Error())
 		case *OkNotification:
-			if buf.Len() > 0 {
-				if err := sendNextChunk(); err != nil {
+			chunk, err := readNextChunk()
+			if err != nil {
+				return nil, poop.Chain(err)
+			}
+			if len(chunk) > 0 {
+				if err := writeSignDataCommand(c.tx, chunk); err != nil {
 					return nil, poop.Chain(err)
 				}
 			} else {
@@ -825,14 +894,13 @@ func (c *Conn) Sign(ctx context.Context, data []byte) ([]byte, error) {
 	}
 }
 
-// SetRadioParams sets the radio parameters.
-func (c *Conn) SetRadioParams(
-	ctx context.Context,
-	radioFreq float64, // how is this represented?
-	radioBw float64,
-	radioSf byte,
-	radioCr byte,
-) error {
+// SetRadioParams sets the radio parameters. The parameters are validated
+// before they are sent to the device.
+func (c *Conn) SetRadioParams(ctx context.Context, params *RadioParams) error {
+	if err := params.Validate(); err != nil {
+		return poop.Chain(err)
+	}
+
 	next, done := iter.Pull2(
 		c.tx.Subscribe(ctx, NotificationTypeOk, NotificationTypeErr),
 	)
@@ -840,10 +908,10 @@ func (c *Conn) SetRadioParams(
 
 	if err := writeSetRadioParamsCommand(
 		c.tx,
-		uint32(radioFreq*1000),
-		uint32(radioBw*1000),
-		radioSf,
-		radioCr,
+		uint32(math.Round(params.Freq*1000)),
+		uint32(math.Round(params.Bw*1000)),
+		params.Sf,
+		params.Cr,
 	); err != nil {
 		return poop.Chain(err)
 	}
@@ -852,6 +920,59 @@ func (c *Conn) SetRadioParams(
 		return poop.Chain(err)
 	}
 
+	switch t := res.(type) {
+	case *OkNotification:
+		if c.dutyCycle != nil {
+			c.dutyCycle.SetRadio(*params)
+		}
+		return nil
+	case *ErrNotification:
+		return poop.Chain(t.Error())
+	}
+
+	panic("unreachable")
+}
+
+// GetTuningParams returns the device's tuning parameters.
+func (c *Conn) GetTuningParams(ctx context.Context) (*TuningParams, error) {
+	next, done := iter.Pull2(
+		c.tx.Subscribe(ctx, NotificationTypeTuningParams, NotificationTypeErr),
+	)
+	defer done()
+
+	if err := writeCommandCode(c.tx, CommandGetTuningParams); err != nil {
+		return nil, poop.Chain(err)
+	}
+	res, err, _ := next()
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	switch t := res.(type) {
+	case *TuningParamsNotification:
+		return &t.TuningParams, nil
+	case *ErrNotification:
+		return nil, poop.Chain(t.Error())
+	}
+
+	panic("unreachable")
+}
+
+// SetTuningParams sets the device's tuning parameters.
+func (c *Conn) SetTuningParams(ctx context.Context, params *TuningParams) error {
+	next, done := iter.Pull2(
+		c.tx.Subscribe(ctx, NotificationTypeOk, NotificationTypeErr),
+	)
+	defer done()
+
+	if err := writeSetTuningParamsCommand(c.tx, params); err != nil {
+		return poop.Chain(err)
+	}
+	res, err, _ := next()
+	if err != nil {
+		return poop.Chain(err)
+	}
+
 	switch t := res.(type) {
 	case *OkNotification:
 		return nil
@@ -868,6 +989,12 @@ func (c *Conn) SendBinaryRequest(
 	recipient PublicKey,
 	payload []byte,
 ) (*BinaryResponse, error) {
+	reservation, err := c.reserveAirtime(binaryRequestPacketSize(payload))
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	defer reservation.release()
+
 	next, done := iter.Pull2(
 		c.tx.Subscribe(ctx, NotificationTypeSent, NotificationTypeBinaryResponse, NotificationTypeErr),
 	)
@@ -876,6 +1003,7 @@ func (c *Conn) SendBinaryRequest(
 	if err := writeSendBinaryRequestCommand(c.tx, recipient, payload); err != nil {
 		return nil, poop.Chain(err)
 	}
+	reservation.keep()
 
 	res, err, _ := next()
 	if err != nil {
@@ -906,6 +1034,41 @@ func (c *Conn) SendBinaryRequest(
 	}
 }
 
+// SendRawData sends a raw data packet along the given path. The device does
+// not flood raw data; an empty path sends it to neighbours only. The payload
+// must be at least 4 bytes.
+func (c *Conn) SendRawData(ctx context.Context, path []byte, payload []byte) error {
+	reservation, err := c.reserveAirtime(rawDataPacketSize(path, payload))
+	if err != nil {
+		return poop.Chain(err)
+	}
+	defer reservation.release()
+
+	next, done := iter.Pull2(
+		c.tx.Subscribe(ctx, NotificationTypeOk, NotificationTypeErr),
+	)
+	defer done()
+
+	if err := writeSendRawDataCommand(c.tx, path, payload); err != nil {
+		return poop.Chain(err)
+	}
+	reservation.keep()
+
+	res, err, _ := next()
+	if err != nil {
+		return poop.Chain(err)
+	}
+
+	switch t := res.(type) {
+	case *OkNotification:
+		return nil
+	case *ErrNotification:
+		return poop.Chain(t.Error())
+	}
+
+	panic("unreachable")
+}
+
 // SetTXPower sets the TX power.
 func (c *Conn) SetTXPower(ctx context.Context, power byte) error {
 	next, done := iter.Pull2(
@@ -1033,6 +1196,12 @@ func (c *Conn) TracePath(ctx context.Context, path []byte) (*TraceData, error) {
 		return nil, poop.Chain(err)
 	}
 
+	reservation, err := c.reserveAirtime(tracePacketSize(path))
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	defer reservation.release()
+
 	next, done := iter.Pull2(
 		c.tx.Subscribe(ctx, NotificationTypeTraceData, NotificationTypeErr),
 	)
@@ -1041,6 +1210,7 @@ func (c *Conn) TracePath(ctx context.Context, path []byte) (*TraceData, error) {
 	if err := writeSendTracePathCommand(c.tx, tag, 0 /* auth */, path); err != nil {
 		return nil, poop.Chain(err)
 	}
+	reservation.keep()
 
 	for {
 		res, err, _ := next()
@@ -1061,6 +1231,12 @@ func (c *Conn) TracePath(ctx context.Context, path []byte) (*TraceData, error) {
 }
 
 func (c *Conn) Login(ctx context.Context, key PublicKey, password string) error {
+	reservation, err := c.reserveAirtime(loginPacketSize(password))
+	if err != nil {
+		return poop.Chain(err)
+	}
+	defer reservation.release()
+
 	next, done := iter.Pull2(
 		c.tx.Subscribe(ctx, NotificationTypeLoginSuccess, NotificationTypeErr),
 	)
@@ -1069,6 +1245,7 @@ func (c *Conn) Login(ctx context.Context, key PublicKey, password string) error
 	if err := writeLoginCommand(c.tx, key, password); err != nil {
 		return poop.Chain(err)
 	}
+	reservation.keep()
 
 	for {
 		res, err, _ := next()
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/json"
+	"io"
 	"iter"
 	"reflect"
 	"testing"
@@ -1447,14 +1448,11 @@ func TestGetSelfInfo(t *testing.T) {
 }
 
 func TestSetRadioParams(t *testing.T) {
-	radioFreq := 910.525 // Is this in MHz?
-	radioBw := 125.0     // This is in kHz
-	radioSf := byte(7)
-	radioCr := byte(5)
+	params := RadioParams{Freq: 910.525, Bw: 125, Sf: 7, Cr: 5}
 
 	t.Run("success", func(t *testing.T) {
 		controller := DoCommand(func(conn *Conn) {
-			if err := conn.SetRadioParams(t.Context(), radioFreq, radioBw, radioSf, radioCr); err != nil {
+			if err := conn.SetRadioParams(t.Context(), &params); err != nil {
 				t.Fatal(err)
 			}
 		})
@@ -1462,10 +1460,10 @@ func TestSetRadioParams(t *testing.T) {
 		if err := ValidateBytes(
 			controller.Recv(),
 			Command(CommandSetRadioParams),
-			Uint32(uint32(radioFreq*1000), binary.LittleEndian),
-			Uint32(uint32(radioBw*1000), binary.LittleEndian),
-			Byte(radioSf),
-			Byte(radioCr),
+			Uint32(910525, binary.LittleEndian),
+			Uint32(125000, binary.LittleEndian),
+			Byte(7),
+			Byte(5),
 		); err != nil {
 			t.Fatal(err)
 		}
@@ -1475,7 +1473,7 @@ func TestSetRadioParams(t *testing.T) {
 
 	t.Run("error", func(t *testing.T) {
 		controller := DoCommand(func(conn *Conn) {
-			if err := conn.SetRadioParams(t.Context(), radioFreq, radioBw, radioSf, radioCr); err == nil || err.Error() != "error: 5 (file io error)" {
+			if err := conn.SetRadioParams(t.Context(), &params); err == nil || err.Error() != "error: 5 (file io error)" {
 				t.Fatalf("expected error: error: 5 (file io error), got %v", err)
 			}
 		})
@@ -1483,10 +1481,10 @@ func TestSetRadioParams(t *testing.T) {
 		if err := ValidateBytes(
 			controller.Recv(),
 			Command(CommandSetRadioParams),
-			Uint32(uint32(radioFreq*1000), binary.LittleEndian),
-			Uint32(uint32(radioBw*1000), binary.LittleEndian),
-			Byte(radioSf),
-			Byte(radioCr),
+			Uint32(910525, binary.LittleEndian),
+			Uint32(125000, binary.LittleEndian),
+			Byte(7),
+			Byte(5),
 		); err != nil {
 			t.Fatal(err)
 		}
@@ -1494,6 +1492,17 @@ func TestSetRadioParams(t *testing.T) {
 		controller.Notify(NotificationTypeErr, BytesFrom(Byte(byte(ErrorCodeFileIOError))))
 		controller.Wait()
 	})
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := params
+		invalid.Sf = 13
+		controller := DoCommand(func(conn *Conn) {
+			if err := conn.SetRadioParams(t.Context(), &invalid); err == nil || err.Error() != "spreading factor must be 5 to 12, got 13" {
+				t.Fatalf("expected error: spreading factor must be 5 to 12, got 13, got %v", err)
+			}
+		})
+		controller.Wait()
+	})
 }
 
 func TestSendBinaryRequest(t *testing.T) {
@@ -2253,3 +2262,152 @@ func TestPushNotifications(t *testing.T) {
 		})
 	}
 }
+
+func TestSignReader(t *testing.T) {
+	t.Run("too long", func(t *testing.T) {
+		data := fakeBytes(200, func(i int) byte {
+			return byte(i)
+		})
+
+		controller := DoCommand(func(conn *Conn) {
+			// io.MultiReader hides the length, so the limit is only found
+			// while streaming.
+			_, err := conn.SignReader(t.Context(), io.MultiReader(bytes.NewReader(data)))
+			if err == nil || err.Error() != "data is too long" {
+				t.Fatalf("expected error: data is too long, got %v", err)
+			}
+		})
+
+		if err := ValidateBytes(controller.Recv(), Command(CommandSignStart)); err != nil {
+			t.Fatal(err)
+		}
+
+		controller.Notify(NotificationTypeSignStart, BytesFrom(
+			Byte(0),
+			Uint32(150, binary.LittleEndian),
+		))
+
+		if err := ValidateBytes(
+			controller.Recv(),
+			Command(CommandSignData),
+			Bytes(data[:128]...),
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		controller.Notify(NotificationTypeOk, nil)
+
+		controller.Wait()
+	})
+}
+
+func TestGetTuningParams(t *testing.T) {
+	expected := &TuningParams{
+		RxDelayBase:   1.5,
+		AirtimeFactor: 2,
+	}
+
+	controller := DoCommand(func(conn *Conn) {
+		params, err := conn.GetTuningParams(t.Context())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(params, expected) {
+			t.Fatalf("expected %s, got %s", describe(expected), describe(params))
+		}
+	})
+
+	if err := ValidateBytes(
+		controller.Recv(),
+		Command(CommandGetTuningParams),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	controller.Notify(NotificationTypeTuningParams, BytesFrom(
+		Uint32(1500, binary.LittleEndian),
+		Uint32(2000, binary.LittleEndian),
+	))
+
+	controller.Wait()
+}
+
+func TestSetTuningParams(t *testing.T) {
+	controller := DoCommand(func(conn *Conn) {
+		if err := conn.SetTuningParams(t.Context(), &TuningParams{
+			RxDelayBase:   1.5,
+			AirtimeFactor: 2,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if err := ValidateBytes(
+		controller.Recv(),
+		Command(CommandSetTuningParams),
+		Uint32(1500, binary.LittleEndian),
+		Uint32(2000, binary.LittleEndian),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	controller.Notify(NotificationTypeOk, nil)
+
+	controller.Wait()
+}
+
+func TestSendRawData(t *testing.T) {
+	path := []byte{0x12, 0x34}
+	payload := []byte{1, 2, 3, 4, 5}
+
+	t.Run("success", func(t *testing.T) {
+		controller := DoCommand(func(conn *Conn) {
+			if err := conn.SendRawData(t.Context(), path, payload); err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		if err := ValidateBytes(
+			controller.Recv(),
+			Command(CommandSendRawData),
+			Byte(byte(len(path))),
+			Bytes(path...),
+			Bytes(payload...),
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		controller.Notify(NotificationTypeOk, nil)
+		controller.Wait()
+	})
+
+	t.Run("error", func(t *testing.T) {
+		controller := DoCommand(func(conn *Conn) {
+			if err := conn.SendRawData(t.Context(), path, payload); err == nil || err.Error() != "error: 3 (table full)" {
+				t.Fatalf("expected error: error: 3 (table full), got %v", err)
+			}
+		})
+
+		if err := ValidateBytes(
+			controller.Recv(),
+			Command(CommandSendRawData),
+			Byte(byte(len(path))),
+			Bytes(path...),
+			Bytes(payload...),
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		controller.Notify(NotificationTypeErr, BytesFrom(Byte(byte(ErrorCodeTableFull))))
+		controller.Wait()
+	})
+
+	t.Run("short payload", func(t *testing.T) {
+		controller := DoCommand(func(conn *Conn) {
+			if err := conn.SendRawData(t.Context(), path, payload[:3]); err == nil || err.Error() != "raw data must be at least 4 bytes" {
+				t.Fatalf("expected error: raw data must be at least 4 bytes, got %v", err)
+			}
+		})
+		controller.Wait()
+	})
+}
@@ -18,8 +18,16 @@ type Contact struct {
 	AdvLat     float64
 	AdvLon     float64
 	LastMod    time.Time
+
+	// flood is set for contacts with no known path, which the device
+	// reaches by flooding rather than directly.
+	flood bool
 }
 
+// floodPathLen is the out path length the device uses for contacts that are
+// reached by flooding.
+const floodPathLen = -1
+
 func (c *Contact) writeTo(w io.Writer) error {
 	if len(c.OutPath) > 64 {
 		return poop.Newf("outPath length is greater than 64")
@@ -40,7 +48,12 @@ func (c *Contact) writeTo(w io.Writer) error {
 		return poop.Chain(err)
 	}
 
-	if err := binary.Write(w, binary.LittleEndian, int8(len(c.OutPath))); err != nil {
+	outPathLen := int8(len(c.OutPath))
+	if c.flood {
+		outPathLen = floodPathLen
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, outPathLen); err != nil {
 		return poop.Chain(err)
 	}
 
@@ -125,4 +138,5 @@ const (
 	ContactTypeChat     ContactType = 1
 	ContactTypeRepeater ContactType = 2
 	ContactTypeRoom     ContactType = 3
+	ContactTypeSensor   ContactType = 4
 )
@@ -0,0 +1,149 @@
+package meshcore
+
+import (
+	"context"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kellegous/poop"
+)
+
+const (
+	contactURIScheme  = "meshcore://"
+	contactURIAddPath = "contact/add"
+)
+
+// ContactURI is a contact shared as a meshcore:// link, the format the
+// MeshCore apps use for sharing contacts and in QR codes. There are two forms:
+//
+//	meshcore://<hex encoded advert packet>
+//	meshcore://contact/add?name=<name>&public_key=<hex key>&type=<type>
+//
+// The first form carries a signed advert and is preferred since the contact
+// can be verified before it is imported.
+type ContactURI struct {
+	// Advert is the signed advert carried by the link, or nil if the link
+	// only carries a name, key and type.
+	Advert    *Advert
+	Name      string
+	PublicKey PublicKey
+	Type      ContactType
+}
+
+// NewContactURIFromAdvert creates a ContactURI carrying the given advert.
+func NewContactURIFromAdvert(advert *Advert) *ContactURI {
+	return &ContactURI{
+		Advert:    advert,
+		Name:      advert.Data.Name,
+		PublicKey: advert.PublicKey,
+		Type:      advert.Data.Type,
+	}
+}
+
+// ParseContactURI parses a meshcore:// contact link.
+func ParseContactURI(s string) (*ContactURI, error) {
+	if len(s) < len(contactURIScheme) || !strings.EqualFold(s[:len(contactURIScheme)], contactURIScheme) {
+		return nil, poop.Newf("contact uri must start with %s", contactURIScheme)
+	}
+	rest := s[len(contactURIScheme):]
+
+	if path, query, ok := strings.Cut(rest, "?"); ok && path == contactURIAddPath {
+		return parseContactURIQuery(query)
+	}
+
+	packet, err := hex.DecodeString(rest)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	advert, err := ParseAdvertPacket(packet)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	return NewContactURIFromAdvert(advert), nil
+}
+
+func parseContactURIQuery(query string) (*ContactURI, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	key, err := ParsePublicKey(values.Get("public_key"))
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	u := &ContactURI{
+		Name:      values.Get("name"),
+		PublicKey: key,
+		Type:      ContactTypeChat,
+	}
+
+	if t := values.Get("type"); t != "" {
+		v, err := strconv.ParseUint(t, 10, 8)
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+		u.Type = ContactType(v)
+	}
+
+	return u, nil
+}
+
+func (u *ContactURI) String() string {
+	if u.Advert != nil {
+		return contactURIScheme + hex.EncodeToString(u.Advert.Packet())
+	}
+
+	values := url.Values{}
+	values.Set("name", u.Name)
+	values.Set("public_key", u.PublicKey.String())
+	values.Set("type", strconv.Itoa(int(u.Type)))
+	return contactURIScheme + contactURIAddPath + "?" + values.Encode()
+}
+
+// ExportContactURI exports a contact from the device as a meshcore:// link.
+// If key is nil, the device's self contact is exported.
+func (c *Conn) ExportContactURI(ctx context.Context, key *PublicKey) (string, error) {
+	packet, err := c.ExportContact(ctx, key)
+	if err != nil {
+		return "", poop.Chain(err)
+	}
+
+	advert, err := ParseAdvertPacket(packet)
+	if err != nil {
+		return "", poop.Chain(err)
+	}
+
+	return NewContactURIFromAdvert(advert).String(), nil
+}
+
+// ImportContactURI imports the contact in a meshcore:// link into the device.
+// Links that carry an advert are rejected if the advert's signature is not
+// valid. Contacts imported from links without an advert have no known path
+// and are reached by flooding.
+func (c *Conn) ImportContactURI(ctx context.Context, uri string) error {
+	u, err := ParseContactURI(uri)
+	if err != nil {
+		return poop.Chain(err)
+	}
+
+	if u.Advert == nil {
+		return c.AddOrUpdateContact(ctx, &Contact{
+			PublicKey: u.PublicKey,
+			Type:      u.Type,
+			AdvName:   u.Name,
+			flood:     true,
+		})
+	}
+
+	if !u.Advert.Verify() {
+		return poop.New("advert has an invalid signature")
+	}
+
+	return c.ImportContact(ctx, u.Advert.Packet())
+}
@@ -0,0 +1,201 @@
+package meshcore
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContactURI(t *testing.T) {
+	key, _ := fakePrivateKey(t, 7)
+	advert, err := NewAdvert(&key, time.Unix(1700000000, 0), &AdvertData{
+		Type:  ContactTypeChat,
+		Flags: AdvertFlagName,
+		Name:  "kellegous",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name     string
+		URI      *ContactURI
+		Expected string
+	}{
+		{
+			Name:     "advert",
+			URI:      NewContactURIFromAdvert(advert),
+			Expected: "meshcore://1100",
+		},
+		{
+			Name: "name, key and type",
+			URI: &ContactURI{
+				Name:      "hill top",
+				PublicKey: fakePublicKey(42),
+				Type:      ContactTypeRepeater,
+			},
+			Expected: "meshcore://contact/add?name=hill+top&public_key=2a00000000000000000000000000000000000000000000000000000000000000&type=2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			s := test.URI.String()
+			if !strings.HasPrefix(s, test.Expected) {
+				t.Fatalf("expected %s to start with %s", s, test.Expected)
+			}
+
+			parsed, err := ParseContactURI(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(parsed, test.URI) {
+				t.Fatalf("expected %s, got %s", describe(test.URI), describe(parsed))
+			}
+		})
+	}
+}
+
+func TestParseContactURIErrors(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Input         string
+		ExpectedError string
+	}{
+		{
+			Name:          "wrong scheme",
+			Input:         "https://example.com",
+			ExpectedError: "contact uri must start with meshcore://",
+		},
+		{
+			Name:          "bad key",
+			Input:         "meshcore://contact/add?name=x&public_key=abcd&type=1",
+			ExpectedError: "public key must be 32 bytes, got 2",
+		},
+		{
+			Name:          "not an advert",
+			Input:         "meshcore://0900",
+			ExpectedError: "packet is not an advert (header 0x09)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if _, err := ParseContactURI(test.Input); err == nil || err.Error() != test.ExpectedError {
+				t.Fatalf("expected error %q, got %v", test.ExpectedError, err)
+			}
+		})
+	}
+}
+
+func TestExportContactURI(t *testing.T) {
+	key, _ := fakePrivateKey(t, 7)
+	advert, err := NewAdvert(&key, time.Unix(1700000000, 0), &AdvertData{
+		Type:  ContactTypeChat,
+		Flags: AdvertFlagName,
+		Name:  "kellegous",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	controller := DoCommand(func(conn *Conn) {
+		uri, err := conn.ExportContactURI(t.Context(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := NewContactURIFromAdvert(advert).String(); uri != expected {
+			t.Fatalf("expected %s, got %s", expected, uri)
+		}
+	})
+
+	if err := ValidateBytes(controller.Recv(), Command(CommandExportContact)); err != nil {
+		t.Fatal(err)
+	}
+
+	controller.Notify(NotificationTypeExportContact, advert.Packet())
+
+	controller.Wait()
+}
+
+func TestImportContactURI(t *testing.T) {
+	key, _ := fakePrivateKey(t, 7)
+	advert, err := NewAdvert(&key, time.Unix(1700000000, 0), &AdvertData{
+		Type:  ContactTypeChat,
+		Flags: AdvertFlagName,
+		Name:  "kellegous",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("advert", func(t *testing.T) {
+		controller := DoCommand(func(conn *Conn) {
+			if err := conn.ImportContactURI(t.Context(), NewContactURIFromAdvert(advert).String()); err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		if err := ValidateBytes(
+			controller.Recv(),
+			Command(CommandImportContact),
+			Bytes(advert.Packet()...),
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		controller.Notify(NotificationTypeOk, nil)
+
+		controller.Wait()
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		forged := *advert
+		forged.Data.Name = "someone else"
+		forged.appData = append([]byte{}, advert.appData...)
+		forged.appData[1] = 'K'
+
+		controller := DoCommand(func(conn *Conn) {
+			err := conn.ImportContactURI(t.Context(), NewContactURIFromAdvert(&forged).String())
+			if err == nil || err.Error() != "advert has an invalid signature" {
+				t.Fatalf("expected error: advert has an invalid signature, got %v", err)
+			}
+		})
+
+		controller.Wait()
+	})
+
+	t.Run("name, key and type", func(t *testing.T) {
+		uri := &ContactURI{
+			Name:      "hill top",
+			PublicKey: fakePublicKey(42),
+			Type:      ContactTypeRepeater,
+		}
+
+		controller := DoCommand(func(conn *Conn) {
+			if err := conn.ImportContactURI(t.Context(), uri.String()); err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		if err := ValidateBytes(
+			controller.Recv(),
+			Command(CommandAddUpdateContact),
+			Bytes(uri.PublicKey.Bytes()...),
+			Byte(byte(ContactTypeRepeater)),
+			Byte(0),
+			Byte(0xff),
+			AnyBytes(64),
+			CString("hill top", 32),
+			AnyBytes(16),
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		controller.Notify(NotificationTypeOk, nil)
+
+		controller.Wait()
+	})
+}
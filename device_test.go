@@ -0,0 +1,171 @@
+package meshcore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"testing"
+)
+
+// fakeDevice is a minimal in-memory device that answers the commands used to
+// read and change a device's configuration.
+type fakeDevice struct {
+	info     SelfInfo
+	tuning   *TuningParams
+	contacts []*Contact
+	adverts  map[PublicKey][]byte
+	channels []*ChannelInfo
+	key      []byte
+
+	// imported holds the advert packets passed to ImportContact.
+	imported [][]byte
+	// commands holds the code of every command received.
+	commands []CommandCode
+}
+
+func newFakeDevice(slots int) *fakeDevice {
+	d := &fakeDevice{
+		adverts: map[PublicKey][]byte{},
+	}
+	for i := range slots {
+		d.channels = append(d.channels, &ChannelInfo{
+			Index:  uint8(i),
+			Secret: make([]byte, 16),
+		})
+	}
+	return d
+}
+
+// serve answers commands sent by the controller's Conn until the operation
+// completes.
+func (d *fakeDevice) serve(t *testing.T, controller *Controller) {
+	for {
+		select {
+		case b := <-controller.tx.ch:
+			d.handle(t, controller, b)
+		case <-controller.tx.done:
+			return
+		}
+	}
+}
+
+func (d *fakeDevice) ok(controller *Controller) {
+	controller.Notify(NotificationTypeOk, nil)
+}
+
+func (d *fakeDevice) fail(controller *Controller, code ErrorCode) {
+	controller.Notify(NotificationTypeErr, BytesFrom(Byte(byte(code))))
+}
+
+func (d *fakeDevice) handle(t *testing.T, controller *Controller, b []byte) {
+	code := CommandCode(b[0])
+	d.commands = append(d.commands, code)
+	r := bytes.NewReader(b[1:])
+
+	switch code {
+	case CommandAppStart:
+		controller.Notify(NotificationTypeSelfInfo, SelfInfoFrom(&d.info))
+	case CommandGetTuningParams:
+		if d.tuning == nil {
+			d.fail(controller, ErrorCodeUnsupportedCommand)
+			return
+		}
+		var buf bytes.Buffer
+		d.tuning.writeTo(&buf)
+		controller.Notify(NotificationTypeTuningParams, buf.Bytes())
+	case CommandSetTuningParams:
+		var params TuningParams
+		if err := params.readFrom(r); err != nil {
+			t.Fatal(err)
+		}
+		d.tuning = &params
+		d.ok(controller)
+	case CommandGetContacts:
+		controller.Notify(NotificationTypeContactsStart, nil)
+		for _, contact := range d.contacts {
+			var buf bytes.Buffer
+			contact.writeTo(&buf)
+			controller.Notify(NotificationTypeContact, buf.Bytes())
+		}
+		controller.Notify(NotificationTypeEndOfContacts, nil)
+	case CommandAddUpdateContact:
+		var contact Contact
+		if err := contact.readFrom(r); err != nil {
+			t.Fatal(err)
+		}
+		d.contacts = slices.DeleteFunc(d.contacts, func(c *Contact) bool {
+			return c.PublicKey == contact.PublicKey
+		})
+		d.contacts = append(d.contacts, &contact)
+		d.ok(controller)
+	case CommandExportContact:
+		var key PublicKey
+		if err := key.readFrom(r); err != nil {
+			t.Fatal(err)
+		}
+		advert, ok := d.adverts[key]
+		if !ok {
+			d.fail(controller, ErrorCodeNotFound)
+			return
+		}
+		controller.Notify(NotificationTypeExportContact, advert)
+	case CommandImportContact:
+		d.imported = append(d.imported, b[1:])
+		d.ok(controller)
+	case CommandGetChannel:
+		idx := int(b[1])
+		if idx >= len(d.channels) {
+			d.fail(controller, ErrorCodeNotFound)
+			return
+		}
+		channel := d.channels[idx]
+		controller.Notify(NotificationTypeChannelInfo, BytesFrom(
+			Byte(channel.Index),
+			CString(channel.Name, 32),
+			Bytes(channel.Secret...),
+		))
+	case CommandSetChannel:
+		var channel ChannelInfo
+		if err := channel.readFrom(r); err != nil {
+			t.Fatal(err)
+		}
+		d.channels[channel.Index] = &channel
+		d.ok(controller)
+	case CommandExportPrivateKey:
+		if d.key == nil {
+			controller.Notify(NotificationTypeDisabled, nil)
+			return
+		}
+		controller.Notify(NotificationTypePrivateKey, d.key)
+	case CommandImportPrivateKey:
+		d.key = bytes.Clone(b[1:])
+		d.ok(controller)
+	case CommandSetAdvertName:
+		d.info.Name = string(b[1:])
+		d.ok(controller)
+	case CommandSetAdvertLatLon:
+		var err error
+		d.info.AdvLat, d.info.AdvLon, err = readLatLon(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.ok(controller)
+	case CommandSetRadioParams:
+		var freq, bw uint32
+		binary.Read(r, binary.LittleEndian, &freq)
+		binary.Read(r, binary.LittleEndian, &bw)
+		d.info.RadioFreq = float64(freq) / 1000
+		d.info.RadioBw = float64(bw) / 1000
+		d.info.RadioSf, _ = r.ReadByte()
+		d.info.RadioCr, _ = r.ReadByte()
+		d.ok(controller)
+	case CommandSetTxPower:
+		d.info.TxPower = b[1]
+		d.ok(controller)
+	case CommandSetOtherParams:
+		d.info.ManualAddContacts = b[1]
+		d.ok(controller)
+	default:
+		d.fail(controller, ErrorCodeUnsupportedCommand)
+	}
+}
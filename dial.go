@@ -0,0 +1,109 @@
+package meshcore
+
+import (
+	"context"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/kellegous/poop"
+)
+
+// Dialer connects to the device at addr, the part of a dial URL between the
+// scheme and the query, with the options given in the query.
+type Dialer func(ctx context.Context, addr string, query url.Values) (*Conn, error)
+
+var dialers = struct {
+	sync.RWMutex
+	m map[string]Dialer
+}{m: map[string]Dialer{}}
+
+// RegisterDialer makes a transport available to Dial under the URL scheme.
+// Transport packages call it from init, so importing one, even for its side
+// effects only, registers it. It panics if the scheme is already registered.
+func RegisterDialer(scheme string, d Dialer) {
+	dialers.Lock()
+	defer dialers.Unlock()
+
+	if _, ok := dialers.m[scheme]; ok {
+		panic("meshcore: dialer already registered for " + scheme)
+	}
+	dialers.m[scheme] = d
+}
+
+// unregisterDialer removes the dialer for scheme. It lets tests register
+// their own dialers more than once.
+func unregisterDialer(scheme string) {
+	dialers.Lock()
+	defer dialers.Unlock()
+	delete(dialers.m, scheme)
+}
+
+// DialSchemes returns the URL schemes that have been registered, in order.
+func DialSchemes() []string {
+	dialers.RLock()
+	defer dialers.RUnlock()
+
+	var schemes []string
+	for scheme := range dialers.m {
+		schemes = append(schemes, scheme)
+	}
+	slices.Sort(schemes)
+	return schemes
+}
+
+// Dial connects to the device at the URL, using the transport registered for
+// its scheme. For example:
+//
+//	serial:///dev/ttyUSB0?baud=115200
+//	ble://MeshCore-abc
+//	ble://AA:BB:CC:DD:EE:FF
+//	tcp://host:5000
+//
+// Options for the transport are given in the query string.
+func Dial(ctx context.Context, rawURL string) (*Conn, error) {
+	scheme, rest, ok := strings.Cut(rawURL, "://")
+	if !ok || scheme == "" {
+		return nil, poop.Newf("invalid device url: %q", rawURL)
+	}
+
+	// Addresses like BLE MACs are not valid URL hosts, so the URL is split
+	// by hand rather than with url.Parse.
+	addr, rawQuery, _ := strings.Cut(rest, "?")
+	addr, err := url.PathUnescape(addr)
+	if err != nil {
+		return nil, poop.ChainWithf(err, "invalid device url: %q", rawURL)
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, poop.ChainWithf(err, "invalid device url: %q", rawURL)
+	}
+
+	dialers.RLock()
+	d, ok := dialers.m[strings.ToLower(scheme)]
+	dialers.RUnlock()
+	if !ok {
+		return nil, poop.Newf("unknown transport %q, registered: %s",
+			scheme, strings.Join(DialSchemes(), ", "))
+	}
+
+	conn, err := d(ctx, addr, query)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	return conn, nil
+}
+
+// CheckDialQuery returns an error if query has options other than known,
+// for use by Dialers.
+func CheckDialQuery(query url.Values, known ...string) error {
+	for _, key := range slices.Sorted(maps.Keys(query)) {
+		if !slices.Contains(known, key) {
+			return poop.Newf("unknown option: %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package meshcore
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDial(t *testing.T) {
+	var gotAddr string
+	var gotQuery url.Values
+	RegisterDialer("dialtest", func(ctx context.Context, addr string, query url.Values) (*Conn, error) {
+		gotAddr, gotQuery = addr, query
+		if err := CheckDialQuery(query, "baud"); err != nil {
+			return nil, err
+		}
+		return NewConnection(&fakeTransport{NotificationCenter: NewNotificationCenter()}), nil
+	})
+	t.Cleanup(func() { unregisterDialer("dialtest") })
+
+	tests := []struct {
+		url   string
+		addr  string
+		query url.Values
+		err   string
+	}{
+		{
+			url:   "dialtest:///dev/ttyUSB0?baud=115200",
+			addr:  "/dev/ttyUSB0",
+			query: url.Values{"baud": {"115200"}},
+		},
+		{
+			url:   "DIALTEST://AA:BB:CC:DD:EE:FF",
+			addr:  "AA:BB:CC:DD:EE:FF",
+			query: url.Values{},
+		},
+		{
+			url:   "dialtest://MeshCore-a%20b",
+			addr:  "MeshCore-a b",
+			query: url.Values{},
+		},
+		{
+			url:   "dialtest://x?parity=odd",
+			addr:  "x",
+			query: url.Values{"parity": {"odd"}},
+			err:   `unknown option: "parity"`,
+		},
+		{
+			url: "/dev/ttyUSB0",
+			err: `invalid device url: "/dev/ttyUSB0"`,
+		},
+		{
+			url: "nope://x",
+			err: `unknown transport "nope", registered: dialtest`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			gotAddr, gotQuery = "", nil
+			conn, err := Dial(t.Context(), test.url)
+			if test.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+			} else if err != nil || conn == nil {
+				t.Fatalf("expected a connection, got %v", err)
+			}
+
+			if gotAddr != test.addr || !reflect.DeepEqual(gotQuery, test.query) {
+				t.Fatalf("expected %q %v, got %q %v", test.addr, test.query, gotAddr, gotQuery)
+			}
+		})
+	}
+}
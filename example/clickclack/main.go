@@ -13,12 +13,12 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/kellegous/meshcore"
-	meshcore_bluetooth "github.com/kellegous/meshcore/bluetooth"
-	meshcore_serial "github.com/kellegous/meshcore/serial"
+	_ "github.com/kellegous/meshcore/bluetooth"
+	_ "github.com/kellegous/meshcore/serial"
+	_ "github.com/kellegous/meshcore/tcp"
 	"github.com/kellegous/poop"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/term"
-	"tinygo.org/x/bluetooth"
 )
 
 type Printer func(format string, a ...any) (int, error)
@@ -79,7 +79,7 @@ func run(ctx context.Context) error {
 
 	flag.Parse()
 	if flag.NArg() != 2 {
-		fmt.Fprintf(os.Stderr, "usage: %s <address> <address>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "usage: %s <url> <url>\n", os.Args[0])
 		os.Exit(1)
 	}
 
@@ -320,41 +320,16 @@ func newActor(
 
 func connect(
 	ctx context.Context,
-	name string,
+	url string,
 	onSend func(code meshcore.CommandCode, data []byte),
 	onRecv func(code meshcore.NotificationCode, data []byte),
 ) (*meshcore.Conn, error) {
-	tx, addr, ok := strings.Cut(name, ":")
-	if !ok {
-		return nil, poop.Newf("invalid name: %s", name)
-	}
-
-	switch tx {
-	case "ble", "bluetooth":
-		client, err := meshcore_bluetooth.NewClient(bluetooth.DefaultAdapter)
-		if err != nil {
-			return nil, poop.Chain(err)
-		}
-		device, err := client.LookupDevice(ctx, addr)
-		if err != nil {
-			return nil, poop.Chain(err)
-		}
-		return client.Connect(
-			ctx,
-			device.Address,
-			meshcore_bluetooth.OnRecv(onRecv),
-			meshcore_bluetooth.OnSend(onSend),
-		)
-	case "usb", "serial":
-		return meshcore_serial.Connect(
-			ctx,
-			addr,
-			meshcore_serial.OnRecv(onRecv),
-			meshcore_serial.OnSend(onSend),
-		)
+	conn, err := meshcore.Dial(ctx, url)
+	if err != nil {
+		return nil, poop.Chain(err)
 	}
-
-	return nil, poop.Newf("invalid transport: %s", tx)
+	conn.Use(meshcore.OnRecv(onRecv), meshcore.OnSend(onSend))
+	return conn, nil
 }
 
 func exchangeContactMessage(
@@ -470,17 +445,15 @@ func getAndSetTime(
 	ctx context.Context,
 	actor *Actor,
 ) error {
-	t, err := actor.GetDeviceTime(ctx)
+	sample, err := meshcore.NewTimeSync(actor.Conn, nil).Check(ctx)
 	if err != nil {
 		return poop.Chain(err)
 	}
-	actor.Printf("device time: %s", t.Format(time.RFC3339))
+	actor.Printf("device time: %s (drift %s)", sample.DeviceTime.Format(time.RFC3339), sample.Drift)
 
-	now := time.Now()
-	if err := actor.SetDeviceTime(ctx, now); err != nil {
-		return poop.Chain(err)
+	if sample.Corrected {
+		actor.Printf("set device time to host time")
 	}
-	actor.Printf("set device time to %s", now.Format(time.RFC3339))
 
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/kellegous/meshcore"
 	"github.com/kellegous/meshcore/serial"
+	_ "github.com/kellegous/meshcore/tcp"
 )
 
 func ExampleConn_SendTextMessage() {
@@ -42,6 +43,16 @@ func ExampleConn_SendTextMessage() {
 	fmt.Printf("sent message: %+v\n", sr)
 }
 
+func ExampleDial() {
+	// Connect to a device by URL. Transports are registered by importing
+	// their packages.
+	conn, err := meshcore.Dial(context.Background(), "serial:///dev/ttyUSB0?baud=115200")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Disconnect()
+}
+
 var (
 	conn    *meshcore.Conn
 	ctx     context.Context
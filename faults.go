@@ -0,0 +1,391 @@
+package meshcore
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrInjectedDisconnect is returned by writes to a FaultTransport after it
+// has injected a disconnect.
+var ErrInjectedDisconnect = errors.New("injected disconnect")
+
+// FaultKind is the kind of fault injected by a FaultTransport.
+type FaultKind int
+
+const (
+	// FaultDrop drops the frame.
+	FaultDrop FaultKind = iota + 1
+	// FaultDuplicate passes the frame on twice.
+	FaultDuplicate
+	// FaultReorder holds the frame back until after the next one.
+	FaultReorder
+	// FaultCorrupt XORs one byte of the frame with a mask.
+	FaultCorrupt
+	// FaultDelay passes the frame on after a delay.
+	FaultDelay
+	// FaultDisconnect drops the frame and fails the transport.
+	FaultDisconnect
+)
+
+func (k FaultKind) String() string {
+	switch k {
+	case FaultDrop:
+		return "drop"
+	case FaultDuplicate:
+		return "duplicate"
+	case FaultReorder:
+		return "reorder"
+	case FaultCorrupt:
+		return "corrupt"
+	case FaultDelay:
+		return "delay"
+	case FaultDisconnect:
+		return "disconnect"
+	}
+	return fmt.Sprintf("FaultKind(%d)", int(k))
+}
+
+// FaultDirection is the direction of the frames a fault applies to.
+type FaultDirection int
+
+const (
+	// FaultSend applies to command frames sent to the device.
+	FaultSend FaultDirection = iota
+	// FaultRecv applies to notification frames received from the device.
+	FaultRecv
+)
+
+func (d FaultDirection) String() string {
+	if d == FaultRecv {
+		return "recv"
+	}
+	return "send"
+}
+
+// Fault is a fault injected into a single frame. Frames are numbered from 0
+// in each direction.
+type Fault struct {
+	Kind      FaultKind
+	Direction FaultDirection
+	Frame     int
+	// Delay is how long a FaultDelay holds the frame.
+	Delay time.Duration
+	// Offset and Mask give the byte a FaultCorrupt changes, where offset 0 is
+	// the command or notification code.
+	Offset int
+	Mask   byte
+}
+
+func (f Fault) String() string {
+	s := fmt.Sprintf("%s %s frame %d", f.Kind, f.Direction, f.Frame)
+	switch f.Kind {
+	case FaultDelay:
+		s += fmt.Sprintf(" by %s", f.Delay)
+	case FaultCorrupt:
+		s += fmt.Sprintf(" at %d with %#02x", f.Offset, f.Mask)
+	}
+	return s
+}
+
+// FaultRates are the probabilities, from 0 to 1, of each kind of fault being
+// injected into a frame. At most one fault is injected per frame.
+type FaultRates struct {
+	Drop       float64
+	Duplicate  float64
+	Reorder    float64
+	Corrupt    float64
+	Delay      float64
+	Disconnect float64
+}
+
+// FaultOptions configures a FaultTransport.
+type FaultOptions struct {
+	// Seed seeds the random faults. The same seed injects the same faults
+	// into the same sequence of frames.
+	Seed uint64
+	// Send are the rates of faults in commands sent to the device.
+	Send FaultRates
+	// Recv are the rates of faults in notifications received from the
+	// device.
+	Recv FaultRates
+	// MaxDelay is the longest random delay, and how long a reordered frame
+	// is held if no other frame follows it. It defaults to 1 second.
+	MaxDelay time.Duration
+	// Schedule lists faults to inject into specific frames, in place of any
+	// random fault. The faults logged by a FaultTransport can be replayed
+	// here with zero rates.
+	Schedule []Fault
+	// Logger receives a record for each injected fault. If nil,
+	// slog.Default() is used.
+	Logger *slog.Logger
+}
+
+// faultStream is the state of the frames in one direction.
+type faultStream struct {
+	direction FaultDirection
+	rates     FaultRates
+	rng       *rand.Rand
+	frames    int
+	held      func()
+}
+
+// FaultTransport wraps a Transport and injects faults into the frames that
+// pass through it, for testing how code copes with an unreliable link.
+type FaultTransport struct {
+	Transport
+	tx     Transport
+	opts   FaultOptions
+	logger *slog.Logger
+	sleep  func(time.Duration)
+
+	mu           sync.Mutex
+	send         faultStream
+	recv         faultStream
+	faults       []Fault
+	disconnected bool
+}
+
+// NewFaultTransport wraps tx in a FaultTransport. The faults in received
+// notifications are only injected if tx publishes through a
+// NotificationCenter.
+func NewFaultTransport(tx Transport, opts *FaultOptions) *FaultTransport {
+	t := &FaultTransport{
+		tx:    tx,
+		sleep: time.Sleep,
+	}
+	if opts != nil {
+		t.opts = *opts
+	}
+	if t.opts.MaxDelay <= 0 {
+		t.opts.MaxDelay = time.Second
+	}
+	t.logger = t.opts.Logger
+	if t.logger == nil {
+		t.logger = slog.Default()
+	}
+
+	// Each direction has its own source so that the faults in one do not
+	// depend on how frames interleave with the other.
+	seed := t.opts.Seed
+	t.send = faultStream{
+		direction: FaultSend,
+		rates:     t.opts.Send,
+		rng:       rand.New(rand.NewPCG(seed, 0)),
+	}
+	t.recv = faultStream{
+		direction: FaultRecv,
+		rates:     t.opts.Recv,
+		rng:       rand.New(rand.NewPCG(seed, 1)),
+	}
+
+	t.Transport = WithMiddleware(tx, Middleware{
+		Send: t.sendMiddleware,
+		Recv: t.recvMiddleware,
+	})
+	return t
+}
+
+// Faults returns the faults injected so far.
+func (t *FaultTransport) Faults() []Fault {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.faults)
+}
+
+func (t *FaultTransport) unwrap() Transport {
+	return t.Transport
+}
+
+// next decides the fault, if any, for the next frame in s. It also returns
+// the frame held back by an earlier reorder, which the caller must release
+// once the frame has been passed on.
+func (t *FaultTransport) next(s *faultStream, size int) (*Fault, func(), bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	held := s.held
+	s.held = nil
+
+	n := s.frames
+	s.frames++
+
+	if t.disconnected {
+		return nil, held, true
+	}
+
+	f := t.scheduled(s.direction, n)
+	if f == nil {
+		f = s.random(n, size, t.opts.MaxDelay)
+	}
+	if f == nil {
+		return nil, held, false
+	}
+
+	t.faults = append(t.faults, *f)
+	attrs := []any{
+		slog.String("fault", f.Kind.String()),
+		slog.String("direction", f.Direction.String()),
+		slog.Int("frame", f.Frame),
+	}
+	switch f.Kind {
+	case FaultDelay:
+		attrs = append(attrs, slog.Duration("delay", f.Delay))
+	case FaultCorrupt:
+		attrs = append(attrs, slog.Int("offset", f.Offset), slog.Int("mask", int(f.Mask)))
+	}
+	t.logger.Info("fault injected", attrs...)
+
+	if f.Kind == FaultDisconnect {
+		t.disconnected = true
+	}
+	return f, held, false
+}
+
+func (t *FaultTransport) scheduled(d FaultDirection, n int) *Fault {
+	for _, f := range t.opts.Schedule {
+		if f.Direction == d && f.Frame == n {
+			return &f
+		}
+	}
+	return nil
+}
+
+func (s *faultStream) random(n, size int, maxDelay time.Duration) *Fault {
+	r := s.rng.Float64()
+	for _, c := range []struct {
+		kind FaultKind
+		rate float64
+	}{
+		{FaultDrop, s.rates.Drop},
+		{FaultDuplicate, s.rates.Duplicate},
+		{FaultReorder, s.rates.Reorder},
+		{FaultCorrupt, s.rates.Corrupt},
+		{FaultDelay, s.rates.Delay},
+		{FaultDisconnect, s.rates.Disconnect},
+	} {
+		if r >= c.rate {
+			r -= c.rate
+			continue
+		}
+
+		f := &Fault{Kind: c.kind, Direction: s.direction, Frame: n}
+		switch c.kind {
+		case FaultDelay:
+			f.Delay = time.Duration(s.rng.Int64N(int64(maxDelay)))
+		case FaultCorrupt:
+			if size > 0 {
+				f.Offset = s.rng.IntN(size)
+			}
+			f.Mask = byte(1 << s.rng.IntN(8))
+		}
+		return f
+	}
+	return nil
+}
+
+// hold holds back fn until the next frame in s, or until MaxDelay has
+// passed.
+func (t *FaultTransport) hold(s *faultStream, fn func()) {
+	var once sync.Once
+	release := func() { once.Do(fn) }
+
+	t.mu.Lock()
+	s.held = release
+	t.mu.Unlock()
+
+	time.AfterFunc(t.opts.MaxDelay, release)
+}
+
+func (t *FaultTransport) disconnect() {
+	if nc := notificationCenterOf(t.tx); nc != nil {
+		nc.Shutdown()
+	}
+}
+
+func corrupt(frame []byte, f *Fault) []byte {
+	frame = slices.Clone(frame)
+	if f.Offset < len(frame) {
+		frame[f.Offset] ^= f.Mask
+	}
+	return frame
+}
+
+func (t *FaultTransport) sendMiddleware(next SendFunc) SendFunc {
+	return func(frame []byte) error {
+		f, held, disconnected := t.next(&t.send, len(frame))
+		if held != nil {
+			defer held()
+		}
+
+		switch {
+		case disconnected:
+			return ErrInjectedDisconnect
+		case f == nil:
+			return next(frame)
+		}
+
+		switch f.Kind {
+		case FaultDrop:
+			return nil
+		case FaultDuplicate:
+			if err := next(frame); err != nil {
+				return err
+			}
+			return next(frame)
+		case FaultReorder:
+			t.hold(&t.send, func() { next(frame) })
+			return nil
+		case FaultCorrupt:
+			return next(corrupt(frame, f))
+		case FaultDelay:
+			t.sleep(f.Delay)
+			return next(frame)
+		case FaultDisconnect:
+			t.disconnect()
+			return ErrInjectedDisconnect
+		}
+		return next(frame)
+	}
+}
+
+func (t *FaultTransport) recvMiddleware(next RecvFunc) RecvFunc {
+	return func(code NotificationCode, data []byte) int {
+		f, held, disconnected := t.next(&t.recv, len(data)+1)
+		if held != nil {
+			defer held()
+		}
+
+		switch {
+		case disconnected:
+			return 0
+		case f == nil:
+			return next(code, data)
+		}
+
+		switch f.Kind {
+		case FaultDrop:
+			return 0
+		case FaultDuplicate:
+			next(code, data)
+			return next(code, data)
+		case FaultReorder:
+			t.hold(&t.recv, func() { next(code, data) })
+			return 0
+		case FaultCorrupt:
+			frame := corrupt(append([]byte{byte(code)}, data...), f)
+			return next(NotificationCode(frame[0]), frame[1:])
+		case FaultDelay:
+			t.sleep(f.Delay)
+			return next(code, data)
+		case FaultDisconnect:
+			t.disconnect()
+			return 0
+		}
+		return next(code, data)
+	}
+}
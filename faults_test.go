@@ -0,0 +1,145 @@
+package meshcore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newFaultTestTransport(opts *FaultOptions) (*FaultTransport, *fakeTransport) {
+	tx := &fakeTransport{
+		ch:                 make(chan []byte, 64),
+		NotificationCenter: NewNotificationCenter(),
+	}
+	return NewFaultTransport(tx, opts), tx
+}
+
+func writtenFrames(tx *fakeTransport) [][]byte {
+	var frames [][]byte
+	for {
+		select {
+		case p := <-tx.ch:
+			frames = append(frames, p)
+		default:
+			return frames
+		}
+	}
+}
+
+func TestFaultTransportSchedule(t *testing.T) {
+	var buf bytes.Buffer
+	ft, tx := newFaultTestTransport(&FaultOptions{
+		Schedule: []Fault{
+			{Kind: FaultDrop, Direction: FaultSend, Frame: 0},
+			{Kind: FaultDuplicate, Direction: FaultSend, Frame: 1},
+			{Kind: FaultReorder, Direction: FaultSend, Frame: 2},
+			{Kind: FaultCorrupt, Direction: FaultSend, Frame: 4, Offset: 1, Mask: 0xff},
+			{Kind: FaultDelay, Direction: FaultSend, Frame: 5, Delay: time.Minute},
+			{Kind: FaultDisconnect, Direction: FaultSend, Frame: 6},
+		},
+		Logger: slog.New(slog.NewTextHandler(&buf, nil)),
+	})
+	var slept []time.Duration
+	ft.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	for i := range 6 {
+		if _, err := ft.Write([]byte{byte(i), 0x10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for range 2 {
+		if _, err := ft.Write([]byte{6}); !errors.Is(err, ErrInjectedDisconnect) {
+			t.Fatalf("expected %v, got %v", ErrInjectedDisconnect, err)
+		}
+	}
+
+	expected := [][]byte{
+		{1, 0x10},
+		{1, 0x10},
+		{3, 0x10},
+		{2, 0x10},
+		{4, 0xef},
+		{5, 0x10},
+	}
+	if frames := writtenFrames(tx); !reflect.DeepEqual(frames, expected) {
+		t.Fatalf("expected %v, got %v", expected, frames)
+	}
+	if !slices.Equal(slept, []time.Duration{time.Minute}) {
+		t.Fatalf("expected a delay of 1m, got %v", slept)
+	}
+	if faults := ft.Faults(); len(faults) != 6 {
+		t.Fatalf("expected 6 faults, got %v", faults)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("fault injected")); n != 6 {
+		t.Fatalf("expected 6 log records, got %d:\n%s", n, buf.String())
+	}
+}
+
+func TestFaultTransportRecv(t *testing.T) {
+	ft, tx := newFaultTestTransport(&FaultOptions{
+		Schedule: []Fault{
+			{Kind: FaultDrop, Direction: FaultRecv, Frame: 0},
+			{Kind: FaultCorrupt, Direction: FaultRecv, Frame: 1, Offset: 1, Mask: 0x01},
+		},
+		Logger: slog.New(slog.DiscardHandler),
+	})
+	conn := NewConnection(ft)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		v, err := conn.GetBatteryVoltage(t.Context())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if v != 3 {
+			t.Errorf("expected 3, got %d", v)
+		}
+	}()
+
+	<-tx.ch
+	for range 2 {
+		tx.Publish(NotificationTypeBatteryVoltage, BytesFrom(Uint16(2, binary.LittleEndian)))
+	}
+	<-done
+}
+
+func TestFaultTransportSeed(t *testing.T) {
+	run := func(opts *FaultOptions) ([]Fault, [][]byte) {
+		opts.Logger = slog.New(slog.DiscardHandler)
+		ft, tx := newFaultTestTransport(opts)
+		ft.sleep = func(time.Duration) {}
+		for i := range 32 {
+			ft.Write([]byte{byte(i), 1, 2, 3})
+		}
+		return ft.Faults(), writtenFrames(tx)
+	}
+
+	rates := FaultRates{
+		Drop:      0.1,
+		Duplicate: 0.1,
+		Reorder:   0.1,
+		Corrupt:   0.1,
+		Delay:     0.1,
+	}
+	faultsA, framesA := run(&FaultOptions{Seed: 42, Send: rates})
+	faultsB, framesB := run(&FaultOptions{Seed: 42, Send: rates})
+	if len(faultsA) == 0 {
+		t.Fatal("expected faults to be injected")
+	}
+	if !reflect.DeepEqual(faultsA, faultsB) || !reflect.DeepEqual(framesA, framesB) {
+		t.Fatalf("expected the same faults for the same seed, got %v and %v", faultsA, faultsB)
+	}
+
+	// Replaying the injected faults produces the same frames.
+	faultsC, framesC := run(&FaultOptions{Schedule: faultsA})
+	if !reflect.DeepEqual(faultsA, faultsC) || !reflect.DeepEqual(framesA, framesC) {
+		t.Fatalf("expected the replay to match, got %v and %v", faultsA, faultsC)
+	}
+}
@@ -3,6 +3,7 @@ module github.com/kellegous/meshcore
 go 1.25.6
 
 require (
+	filippo.io/edwards25519 v1.2.0
 	github.com/fatih/color v1.18.0
 	github.com/kellegous/poop v0.7.0
 	go.bug.st/serial v1.6.4
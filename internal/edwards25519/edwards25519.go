@@ -0,0 +1,96 @@
+// Package edwards25519 implements the few ed25519 operations that MeshCore
+// needs but the standard library does not expose. MeshCore firmware stores
+// private keys in their expanded form (the clamped scalar followed by the
+// signing prefix) rather than as a seed, so signing with those keys cannot be
+// done with crypto/ed25519.
+//
+// The arithmetic is done by filippo.io/edwards25519, the constant time
+// implementation behind crypto/ed25519.
+package edwards25519
+
+import (
+	"crypto/sha512"
+
+	"filippo.io/edwards25519"
+	"github.com/kellegous/poop"
+)
+
+// scalar returns the secret scalar of the expanded private key.
+func scalar(priv *[64]byte) *edwards25519.Scalar {
+	s, err := edwards25519.NewScalar().SetBytesWithClamping(priv[:32])
+	if err != nil {
+		// Only returned for input that is not 32 bytes.
+		panic(err)
+	}
+	return s
+}
+
+// scalarFromHash reduces a SHA-512 digest to a scalar.
+func scalarFromHash(h []byte) *edwards25519.Scalar {
+	s, err := edwards25519.NewScalar().SetUniformBytes(h)
+	if err != nil {
+		// Only returned for input that is not 64 bytes.
+		panic(err)
+	}
+	return s
+}
+
+// PublicKey returns the encoded public key for the expanded private key.
+func PublicKey(priv *[64]byte) [32]byte {
+	var pub [32]byte
+	copy(pub[:], new(edwards25519.Point).ScalarBaseMult(scalar(priv)).Bytes())
+	return pub
+}
+
+// Sign signs message with the expanded private key and its public key. It
+// produces the same signatures as crypto/ed25519 does for the seed the
+// expanded key was derived from.
+func Sign(priv *[64]byte, pub *[32]byte, message []byte) [64]byte {
+	h := sha512.New()
+	h.Write(priv[32:])
+	h.Write(message)
+	r := scalarFromHash(h.Sum(nil))
+	R := new(edwards25519.Point).ScalarBaseMult(r).Bytes()
+
+	h.Reset()
+	h.Write(R)
+	h.Write(pub[:])
+	h.Write(message)
+	k := scalarFromHash(h.Sum(nil))
+
+	s := edwards25519.NewScalar().MultiplyAdd(k, scalar(priv), r)
+
+	var sig [64]byte
+	copy(sig[:32], R)
+	copy(sig[32:], s.Bytes())
+	return sig
+}
+
+// Expand derives the expanded private key for an ed25519 seed.
+func Expand(seed []byte) [64]byte {
+	var priv [64]byte
+	h := sha512.Sum512(seed)
+	copy(priv[:], h[:])
+	priv[0] &= 248
+	priv[31] &= 63
+	priv[31] |= 64
+	return priv
+}
+
+// MontgomeryU converts an encoded public key to the u-coordinate of the
+// equivalent point on curve25519, which is the public key form used by
+// X25519.
+func MontgomeryU(pub *[32]byte) ([32]byte, error) {
+	var out [32]byte
+
+	p, err := new(edwards25519.Point).SetBytes(pub[:])
+	if err != nil {
+		return out, poop.New("invalid point encoding")
+	}
+	if p.Equal(edwards25519.NewIdentityPoint()) == 1 {
+		return out, poop.New("point is the identity")
+	}
+
+	copy(out[:], p.BytesMontgomery())
+	return out, nil
+}
@@ -0,0 +1,69 @@
+package edwards25519
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+)
+
+func TestMatchesStandardLibrary(t *testing.T) {
+	for i := range 8 {
+		seed := bytes.Repeat([]byte{byte(i * 31)}, ed25519.SeedSize)
+		key := ed25519.NewKeyFromSeed(seed)
+
+		priv := Expand(seed)
+		pub := PublicKey(&priv)
+		if !bytes.Equal(pub[:], key.Public().(ed25519.PublicKey)) {
+			t.Fatalf("expected public key %x, got %x", key.Public(), pub)
+		}
+
+		message := []byte("Hello, world!")
+		sig := Sign(&priv, &pub, message)
+		if expected := ed25519.Sign(key, message); !bytes.Equal(sig[:], expected) {
+			t.Fatalf("expected signature %x, got %x", expected, sig)
+		}
+	}
+}
+
+func TestRFC8032(t *testing.T) {
+	// TEST 1 from RFC 8032 section 7.1.
+	seed, _ := hex.DecodeString("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+	expectedPub := "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"
+	expectedSig := "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e06522490155" +
+		"5fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b"
+
+	priv := Expand(seed)
+	pub := PublicKey(&priv)
+	if hex.EncodeToString(pub[:]) != expectedPub {
+		t.Fatalf("expected public key %s, got %x", expectedPub, pub)
+	}
+
+	sig := Sign(&priv, &pub, nil)
+	if hex.EncodeToString(sig[:]) != expectedSig {
+		t.Fatalf("expected signature %s, got %x", expectedSig, sig)
+	}
+}
+
+func TestMontgomeryU(t *testing.T) {
+	// Known answer from libsodium's crypto_sign_ed25519_pk_to_curve25519
+	// test.
+	seed, _ := hex.DecodeString("421151a459faeade3d247115f94aedae42318124095afabe4d1451a559faedee")
+	expected := "f1814f0e8ff1043d8a44d25babff3cedcae6c22c3edaa48f857ae70de2baae50"
+
+	var pub [32]byte
+	copy(pub[:], ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey))
+
+	u, err := MontgomeryU(&pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(u[:]) != expected {
+		t.Fatalf("expected %s, got %x", expected, u)
+	}
+
+	identity := [32]byte{1}
+	if _, err := MontgomeryU(&identity); err == nil || err.Error() != "point is the identity" {
+		t.Fatalf("expected an identity error, got %v", err)
+	}
+}
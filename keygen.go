@@ -0,0 +1,139 @@
+package meshcore
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/kellegous/poop"
+
+	"github.com/kellegous/meshcore/internal/edwards25519"
+)
+
+// VanityOptions configures FindVanityKey.
+type VanityOptions struct {
+	// Workers is the number of keys to try in parallel. It defaults to the
+	// number of CPUs.
+	Workers int
+}
+
+// reservedKeyPrefix reports whether the first byte of a public key is one of
+// the path hashes the firmware reserves. The firmware never generates keys
+// starting with these bytes.
+func reservedKeyPrefix(b byte) bool {
+	return b == 0x00 || b == 0xff
+}
+
+// generateKey generates a random seed and returns the public key derived from
+// it. Only the seed is kept, so the expanded key is built just for the match.
+func generateKey() (seed []byte, pub ed25519.PublicKey, err error) {
+	seed = make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, nil, poop.Chain(err)
+	}
+	return seed, ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey), nil
+}
+
+func privateKeyFromSeed(seed []byte) PrivateKey {
+	return newPrivateKey(edwards25519.Expand(seed))
+}
+
+// GeneratePrivateKey generates a new identity that can be imported into a
+// device with Conn.ImportPrivateKey.
+func GeneratePrivateKey() (PrivateKey, error) {
+	for {
+		seed, pub, err := generateKey()
+		if err != nil {
+			return PrivateKey{}, poop.Chain(err)
+		}
+		if !reservedKeyPrefix(pub[0]) {
+			return privateKeyFromSeed(seed), nil
+		}
+	}
+}
+
+// FindVanityKey generates identities until it finds one whose hex encoded
+// public key starts with prefix. Each additional hex digit in prefix makes
+// the search take 16 times longer on average. The search stops when ctx is
+// done.
+func FindVanityKey(ctx context.Context, prefix string, opts *VanityOptions) (PrivateKey, error) {
+	prefix = strings.ToLower(prefix)
+	if len(prefix) > 2*ed25519.PublicKeySize {
+		return PrivateKey{}, poop.Newf("prefix must be at most %d hex digits", 2*ed25519.PublicKeySize)
+	}
+	if _, err := hex.DecodeString(prefix + strings.Repeat("0", len(prefix)%2)); err != nil {
+		return PrivateKey{}, poop.Newf("prefix must be hex: %s", prefix)
+	}
+
+	workers := runtime.NumCPU()
+	if opts != nil && opts.Workers > 0 {
+		workers = opts.Workers
+	}
+
+	// Compare the raw bytes for the whole digits of prefix and the high
+	// nibble for a trailing odd digit.
+	want, _ := hex.DecodeString(prefix[:len(prefix)&^1])
+	var nibble byte
+	odd := len(prefix)%2 == 1
+	if odd {
+		b, _ := hex.DecodeString(prefix[len(prefix)-1:] + "0")
+		nibble = b[0]
+	}
+
+	if len(want) > 0 && reservedKeyPrefix(want[0]) {
+		return PrivateKey{}, poop.Newf("prefix %s is reserved", prefix[:2])
+	}
+
+	matches := func(pub ed25519.PublicKey) bool {
+		if reservedKeyPrefix(pub[0]) || !bytes.HasPrefix(pub, want) {
+			return false
+		}
+		return !odd || pub[len(want)]&0xf0 == nibble
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	found := make(chan []byte, 1)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				seed, pub, err := generateKey()
+				if err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+				if !matches(pub) {
+					continue
+				}
+				select {
+				case found <- seed:
+				default:
+				}
+				cancel()
+				return
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	select {
+	case seed := <-found:
+		return privateKeyFromSeed(seed), nil
+	case err := <-errs:
+		return PrivateKey{}, poop.Chain(err)
+	default:
+		return PrivateKey{}, poop.Chain(ctx.Err())
+	}
+}
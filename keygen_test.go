@@ -0,0 +1,58 @@
+package meshcore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGeneratePrivateKey(t *testing.T) {
+	key, err := GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub := key.PublicKey()
+	if b := pub.Prefix(1)[0]; b == 0x00 || b == 0xff {
+		t.Fatalf("generated key has reserved prefix %02x", b)
+	}
+
+	data := []byte("Hello, world!")
+	if !pub.Verify(data, key.Sign(data)) {
+		t.Fatal("expected signature to verify")
+	}
+}
+
+func TestFindVanityKey(t *testing.T) {
+	for _, prefix := range []string{"ab", "C0d"} {
+		key, err := FindVanityKey(t.Context(), prefix, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub := key.PublicKey()
+		if !strings.HasPrefix(pub.String(), strings.ToLower(prefix)) {
+			t.Fatalf("expected key starting with %s, got %s", prefix, pub.String())
+		}
+	}
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		if _, err := FindVanityKey(ctx, "0123456789abcdef", &VanityOptions{Workers: 2}); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for prefix, expected := range map[string]string{
+			"xyz":                   "prefix must be hex: xyz",
+			"ff12":                  "prefix ff is reserved",
+			strings.Repeat("a", 65): "prefix must be at most 64 hex digits",
+		} {
+			if _, err := FindVanityKey(t.Context(), prefix, nil); err == nil || err.Error() != expected {
+				t.Fatalf("expected error %q, got %v", expected, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package meshcore
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSealPrivateKey(t *testing.T) {
+	key, _ := fakePrivateKey(t, 42)
+
+	sealed, err := SealPrivateKey(&key, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := OpenPrivateKey(sealed, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened.Bytes(), key.Bytes()) {
+		t.Fatalf("expected %x, got %x", key.Bytes(), opened.Bytes())
+	}
+
+	if _, err := OpenPrivateKey(sealed, "hunter3"); err == nil || err.Error() != "incorrect passphrase or corrupt keystore" {
+		t.Fatalf("expected error: incorrect passphrase or corrupt keystore, got %v", err)
+	}
+
+	other, _ := fakePrivateKey(t, 7)
+	otherPub := other.PublicKey()
+	keyPub := key.PublicKey()
+	tampered := bytes.Replace(sealed, []byte(keyPub.String()), []byte(otherPub.String()), 1)
+	if _, err := OpenPrivateKey(tampered, "hunter2"); err == nil {
+		t.Fatal("expected error opening keystore with a tampered public key")
+	}
+
+	expensive := bytes.Replace(sealed, []byte("Iterations: 600000"), []byte("Iterations: 2147483647"), 1)
+	if _, err := OpenPrivateKey(expensive, "hunter2"); err == nil || err.Error() != "invalid iterations: 2147483647" {
+		t.Fatalf("expected error: invalid iterations: 2147483647, got %v", err)
+	}
+
+	if _, err := SealPrivateKey(&key, ""); err == nil || err.Error() != "passphrase is empty" {
+		t.Fatalf("expected error: passphrase is empty, got %v", err)
+	}
+}
+
+func TestSaveLoadPrivateKey(t *testing.T) {
+	key, _ := fakePrivateKey(t, 42)
+	path := filepath.Join(t.TempDir(), "identity.pem")
+
+	if err := SavePrivateKey(path, &key, "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("expected mode 0600, got %o", perm)
+	}
+
+	loaded, err := LoadPrivateKey(path, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.Bytes(), key.Bytes()) {
+		t.Fatalf("expected %x, got %x", key.Bytes(), loaded.Bytes())
+	}
+}
+
+func TestEncryptedPrivateKeyExportImport(t *testing.T) {
+	key, _ := fakePrivateKey(t, 42)
+
+	var sealed []byte
+	controller := DoCommand(func(conn *Conn) {
+		var err error
+		sealed, err = conn.ExportEncryptedPrivateKey(t.Context(), "hunter2")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if err := ValidateBytes(
+		controller.Recv(),
+		Command(CommandExportPrivateKey),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	controller.Notify(NotificationTypePrivateKey, BytesFrom(Bytes(key.Bytes()...)))
+
+	controller.Wait()
+
+	controller = DoCommand(func(conn *Conn) {
+		if err := conn.ImportEncryptedPrivateKey(t.Context(), sealed, "hunter2"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if err := ValidateBytes(
+		controller.Recv(),
+		Command(CommandImportPrivateKey),
+		Bytes(key.Bytes()...),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	controller.Notify(NotificationTypeOk, nil)
+
+	controller.Wait()
+}
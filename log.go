@@ -0,0 +1,200 @@
+package meshcore
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// LogOptions configures the logging of a Conn and its transport.
+//
+// Command and notification payloads are never logged whole, so private keys,
+// passwords and message text do not end up in logs.
+type LogOptions struct {
+	// Logger receives the log records. If nil, slog.Default() is used.
+	Logger *slog.Logger
+	// CommandLevel is the level of records for commands being sent and
+	// answered.
+	CommandLevel slog.Level
+	// NotificationLevel is the level of records for notifications.
+	NotificationLevel slog.Level
+}
+
+// logger logs the commands written to a transport and the notifications
+// published by its NotificationCenter.
+type logger struct {
+	opts LogOptions
+	now  func() time.Time
+
+	mu      sync.Mutex
+	pending pendingCommands
+}
+
+func newLogger(opts *LogOptions) *logger {
+	l := &logger{now: time.Now}
+	if opts != nil {
+		l.opts = *opts
+	}
+	if l.opts.Logger == nil {
+		l.opts.Logger = slog.Default()
+	}
+	return l
+}
+
+func (l *logger) command(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	code := CommandCode(data[0])
+
+	l.mu.Lock()
+	l.pending.push(code, l.now())
+	l.mu.Unlock()
+
+	l.opts.Logger.Log(context.Background(), l.opts.CommandLevel, "command sent",
+		slog.String("command", codeLabel(code)),
+		slog.Int("size", len(data)-1))
+}
+
+func (l *logger) notification(code NotificationCode, n Notification, err error, subscribers int) {
+	ctx := context.Background()
+	if err != nil {
+		l.opts.Logger.Log(ctx, slog.LevelWarn, "notification decode failed",
+			slog.String("notification", codeLabel(code)),
+			slog.Any("error", err))
+		return
+	}
+
+	l.mu.Lock()
+	p, ok := l.pending.pop(code)
+	l.mu.Unlock()
+
+	if ok {
+		attrs := []slog.Attr{
+			slog.String("command", codeLabel(p.code)),
+			slog.Duration("duration", l.now().Sub(p.at)),
+		}
+		if t, ok := n.(*ErrNotification); ok {
+			attrs = append(attrs, slog.Any("error", t.Error()))
+		}
+		l.opts.Logger.LogAttrs(ctx, l.opts.CommandLevel, "command finished", attrs...)
+	}
+
+	attrs := append([]slog.Attr{
+		slog.String("notification", codeLabel(code)),
+		slog.Int("subscribers", subscribers),
+	}, notificationAttrs(n)...)
+	l.opts.Logger.LogAttrs(ctx, l.opts.NotificationLevel, "notification", attrs...)
+}
+
+// notificationAttrs summarizes a notification.
+func notificationAttrs(n Notification) []slog.Attr {
+	switch t := n.(type) {
+	case *ErrNotification:
+		return []slog.Attr{slog.Any("error", t.Error())}
+	case *SelfInfoNotification:
+		return []slog.Attr{
+			slog.String("name", t.SelfInfo.Name),
+			slog.String("public_key", t.SelfInfo.PublicKey.String()),
+		}
+	case *SentNotification:
+		return []slog.Attr{
+			slog.Uint64("expected_ack", uint64(t.ExpectedAckCRC)),
+			slog.Uint64("timeout_ms", uint64(t.EstTimeout)),
+		}
+	case *ContactMsgRecvNotification:
+		return contactMessageAttrs(t.ContactMessage.PubKeyPrefix[:], t.ContactMessage.Text)
+	case *ContactMsgRecvV3Notification:
+		return contactMessageAttrs(t.PublicKeyPrefix[:], t.Text)
+	case *ChannelMsgRecvNotification:
+		return channelMessageAttrs(t.ChannelMessage.ChannelIndex, t.ChannelMessage.Text)
+	case *ChannelMsgRecvV3Notification:
+		return channelMessageAttrs(t.ChannelIndex, t.Text)
+	case *CurrTimeNotification:
+		return []slog.Attr{slog.Time("time", t.Time)}
+	case *BatteryVoltageNotification:
+		return []slog.Attr{slog.Int("millivolts", int(t.Voltage))}
+	case *StatusNotification:
+		return []slog.Attr{slog.String("prefix", hex.EncodeToString(t.Status.PubKeyPrefix[:]))}
+	case *LogRxDataNotification:
+		return signalAttrs(t.LastRSSI, t.LastSNR, len(t.Payload))
+	case *RawDataNotification:
+		return signalAttrs(t.LastRSSI, t.LastSNR, len(t.Payload))
+	}
+	return nil
+}
+
+func contactMessageAttrs(prefix []byte, text string) []slog.Attr {
+	return []slog.Attr{
+		slog.String("prefix", hex.EncodeToString(prefix)),
+		slog.Int("text_len", len(text)),
+	}
+}
+
+func channelMessageAttrs(idx byte, text string) []slog.Attr {
+	return []slog.Attr{
+		slog.Int("channel", int(idx)),
+		slog.Int("text_len", len(text)),
+	}
+}
+
+func signalAttrs(rssi int8, snr float64, size int) []slog.Attr {
+	return []slog.Attr{
+		slog.Int("rssi", int(rssi)),
+		slog.Float64("snr", snr),
+		slog.Int("size", size),
+	}
+}
+
+// LogValue keeps private keys out of logs.
+func (k PrivateKey) LogValue() slog.Value {
+	return slog.StringValue("[redacted]")
+}
+
+// LogValue keeps private keys out of logs.
+func (e *PrivateKeyNotification) LogValue() slog.Value {
+	return slog.StringValue("[redacted]")
+}
+
+// LogMiddleware returns a Middleware that logs the commands and
+// notifications passing through a transport.
+func LogMiddleware(opts *LogOptions) Middleware {
+	return newLogger(opts).middleware()
+}
+
+func (l *logger) middleware() Middleware {
+	mw := Middleware{
+		Send: func(next SendFunc) SendFunc {
+			return func(frame []byte) error {
+				l.command(frame)
+				err := next(frame)
+				if err != nil && len(frame) > 0 {
+					level := max(l.opts.CommandLevel, slog.LevelWarn)
+					l.opts.Logger.Log(context.Background(), level, "command write failed",
+						slog.String("command", codeLabel(CommandCode(frame[0]))),
+						slog.Any("error", err))
+				}
+				return err
+			}
+		},
+	}
+	mw.Recv, mw.frameRecv = frameMiddleware(func(next frameRecvFunc) frameRecvFunc {
+		return func(f *recvFrame) int {
+			delivered := next(f)
+			n, err := f.notification()
+			l.notification(f.code, n, err, delivered)
+			return delivered
+		}
+	})
+	return mw
+}
+
+// SetLogging logs the commands sent on the connection and, if the transport
+// publishes through a NotificationCenter, the notifications it receives. It
+// is shorthand for c.Use(LogMiddleware(opts)).
+func (c *Conn) SetLogging(opts *LogOptions) {
+	c.Use(LogMiddleware(opts))
+}
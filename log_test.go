@@ -0,0 +1,159 @@
+package meshcore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+type logRecord map[string]any
+
+func readLogRecords(t *testing.T, buf *bytes.Buffer) []logRecord {
+	var records []logRecord
+	d := json.NewDecoder(buf)
+	for d.More() {
+		var r logRecord
+		if err := d.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestSetLogging(t *testing.T) {
+	var buf bytes.Buffer
+	key := fakeBytes(64, func(i int) byte { return byte(i + 1) })
+
+	controller := DoCommand(func(conn *Conn) {
+		l := newLogger(&LogOptions{
+			Logger:            slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+			CommandLevel:      slog.LevelInfo,
+			NotificationLevel: slog.LevelDebug,
+		})
+		l.now = func() time.Time { return time.Unix(0, 0) }
+		conn.Use(l.middleware())
+
+		if _, err := conn.GetBatteryVoltage(t.Context()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.ExportPrivateKey(t.Context()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	controller.Recv()
+	controller.Notify(NotificationTypeBatteryVoltage, BytesFrom(Uint16(3987, binary.LittleEndian)))
+	controller.Recv()
+	controller.Notify(NotificationTypePrivateKey, key)
+	controller.Wait()
+
+	// A truncated notification fails to decode.
+	controller.Notify(NotificationTypeBatteryVoltage, []byte{1})
+
+	if bytes.Contains(buf.Bytes(), []byte(hex.EncodeToString(key))) {
+		t.Fatalf("private key was logged:\n%s", buf.String())
+	}
+
+	records := readLogRecords(t, &buf)
+	var msgs []string
+	for _, r := range records {
+		msgs = append(msgs, r["msg"].(string))
+	}
+	expected := []string{
+		"command sent",
+		"command finished",
+		"notification",
+		"command sent",
+		"command finished",
+		"notification",
+		"notification decode failed",
+	}
+	if !slices.Equal(msgs, expected) {
+		t.Fatalf("expected %v, got %v", expected, msgs)
+	}
+
+	if r := records[1]; r["level"] != "INFO" || r["command"] != "GetBatteryVoltage" || r["duration"] != float64(0) {
+		t.Fatalf("unexpected record: %v", r)
+	}
+	if r := records[2]; r["level"] != "DEBUG" || r["notification"] != "BatteryVoltage" || r["millivolts"] != float64(3987) {
+		t.Fatalf("unexpected record: %v", r)
+	}
+	if r := records[6]; r["level"] != "WARN" || r["notification"] != "BatteryVoltage" {
+		t.Fatalf("unexpected record: %v", r)
+	}
+}
+
+func TestLogWriteFailed(t *testing.T) {
+	tests := []struct {
+		Name         string
+		CommandLevel slog.Level
+		Expected     string
+	}{
+		{Name: "debug", CommandLevel: slog.LevelDebug, Expected: "WARN"},
+		{Name: "error", CommandLevel: slog.LevelError, Expected: "ERROR"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := newLogger(&LogOptions{
+				Logger:       slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+				CommandLevel: test.CommandLevel,
+			})
+			send := l.middleware().Send(func(frame []byte) error {
+				return errors.New("write failed")
+			})
+			if err := send([]byte{byte(CommandGetBatteryVoltage)}); err == nil {
+				t.Fatal("expected the write to fail")
+			}
+
+			records := readLogRecords(t, &buf)
+			if len(records) != 2 {
+				t.Fatalf("expected 2 records, got %v", records)
+			}
+			if r := records[1]; r["msg"] != "command write failed" || r["level"] != test.Expected {
+				t.Fatalf("unexpected record: %v", r)
+			}
+		})
+	}
+}
+
+func TestPrivateKeyLogValue(t *testing.T) {
+	var buf bytes.Buffer
+	key, _ := fakePrivateKey(t, 1)
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("key", "key", key)
+	if bytes.Contains(buf.Bytes(), []byte(hex.EncodeToString(key.Bytes()))) || !bytes.Contains(buf.Bytes(), []byte("[redacted]")) {
+		t.Fatalf("private key was not redacted: %s", buf.String())
+	}
+}
+
+func TestSetLoggingWithMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewMetrics()
+
+	controller := DoCommand(func(conn *Conn) {
+		conn.SetMetrics(m)
+		conn.SetLogging(&LogOptions{Logger: slog.New(slog.NewJSONHandler(&buf, nil))})
+		if _, err := conn.GetBatteryVoltage(t.Context()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	controller.Recv()
+	controller.Notify(NotificationTypeBatteryVoltage, BytesFrom(Uint16(3987, binary.LittleEndian)))
+	controller.Wait()
+
+	if records := readLogRecords(t, &buf); len(records) != 3 {
+		t.Fatalf("expected 3 records, got %v", records)
+	}
+	if c := m.commands[CommandGetBatteryVoltage]; c == nil || c.count != 1 || c.timed != 1 {
+		t.Fatalf("expected metrics for the command, got %v", c)
+	}
+}
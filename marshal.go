@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"io"
+	"math"
 	"time"
 
 	"github.com/kellegous/poop"
@@ -71,6 +72,40 @@ func (s *SelfInfo) readFrom(r io.Reader) error {
 	return nil
 }
 
+// TuningParams are the parameters that control how the device schedules
+// retransmissions.
+type TuningParams struct {
+	// RxDelayBase scales the delay before retransmitting a flood packet by
+	// the packet's signal strength. Zero disables it.
+	RxDelayBase float64
+	// AirtimeFactor is the multiple of a packet's airtime the device waits
+	// before transmitting again.
+	AirtimeFactor float64
+}
+
+func (p *TuningParams) readFrom(r io.Reader) error {
+	var rxDelay, airtime uint32
+	if err := binary.Read(r, binary.LittleEndian, &rxDelay); err != nil {
+		return poop.Chain(err)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &airtime); err != nil {
+		return poop.Chain(err)
+	}
+	p.RxDelayBase = float64(rxDelay) / 1000
+	p.AirtimeFactor = float64(airtime) / 1000
+	return nil
+}
+
+func (p *TuningParams) writeTo(w io.Writer) error {
+	if err := binary.Write(w, binary.LittleEndian, uint32(p.RxDelayBase*1000)); err != nil {
+		return poop.Chain(err)
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(p.AirtimeFactor*1000)); err != nil {
+		return poop.Chain(err)
+	}
+	return nil
+}
+
 type ChannelInfo struct {
 	Index  uint8
 	Name   string
@@ -294,6 +329,46 @@ func (s *Status) readFrom(r io.Reader) error {
 	return nil
 }
 
+// RepeaterStats is the status reported by a repeater. Fields that older
+// firmware does not report are zero.
+type RepeaterStats struct {
+	BatteryMillivolts uint16
+	TxQueueLen        uint16
+	NoiseFloor        int16
+	LastRSSI          int16
+	PacketsRecv       uint32
+	PacketsSent       uint32
+	// TxAirtimeSecs is the total time spent transmitting, in seconds.
+	TxAirtimeSecs uint32
+	UptimeSecs    uint32
+	SentFlood     uint32
+	SentDirect    uint32
+	RecvFlood     uint32
+	RecvDirect    uint32
+	ErrEvents     uint16
+	// LastSNR is the SNR of the last packet received, multiplied by 4.
+	LastSNR    int16
+	DirectDups uint16
+	FloodDups  uint16
+	// RxAirtimeSecs is the total time spent receiving, in seconds.
+	RxAirtimeSecs uint32
+}
+
+// RepeaterStats parses the status data sent by a repeater.
+func (s *Status) RepeaterStats() (*RepeaterStats, error) {
+	var stats RepeaterStats
+	if len(s.StatusData) < 2 {
+		return nil, poop.New("status data is too short")
+	}
+
+	b := make([]byte, binary.Size(&stats))
+	copy(b, s.StatusData)
+	if err := binary.Read(bytes.NewReader(b), binary.LittleEndian, &stats); err != nil {
+		return nil, poop.Chain(err)
+	}
+	return &stats, nil
+}
+
 type BinaryResponse struct {
 	Tag          uint32
 	ResponseData []byte
@@ -409,11 +484,17 @@ func readLatLon(r io.Reader) (float64, float64, error) {
 	return float64(lat) / 1e6, float64(lon) / 1e6, nil
 }
 
+// microDegrees converts degrees to the millionths of a degree that the device
+// stores coordinates as.
+func microDegrees(deg float64) int32 {
+	return int32(math.Round(deg * 1e6))
+}
+
 func writeLatLon(w io.Writer, lat, lon float64) error {
-	if err := binary.Write(w, binary.LittleEndian, uint32(lat*1e6)); err != nil {
+	if err := binary.Write(w, binary.LittleEndian, microDegrees(lat)); err != nil {
 		return poop.Chain(err)
 	}
-	if err := binary.Write(w, binary.LittleEndian, uint32(lon*1e6)); err != nil {
+	if err := binary.Write(w, binary.LittleEndian, microDegrees(lon)); err != nil {
 		return poop.Chain(err)
 	}
 	return nil
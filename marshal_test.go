@@ -2,6 +2,7 @@ package meshcore
 
 import (
 	"bytes"
+	"encoding/binary"
 	"testing"
 	"time"
 
@@ -176,3 +177,77 @@ func TestWriteTime(t *testing.T) {
 		})
 	}
 }
+
+func TestRepeaterStats(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Data     []byte
+		Expected *RepeaterStats
+		Error    error
+	}{
+		{
+			Name: "full",
+			Data: BytesFrom(
+				Uint16(4012, binary.LittleEndian),
+				Uint16(1, binary.LittleEndian),
+				Int16(-110, binary.LittleEndian),
+				Int16(-80, binary.LittleEndian),
+				Uint32(100, binary.LittleEndian),
+				Uint32(50, binary.LittleEndian),
+				Uint32(30, binary.LittleEndian),
+				Uint32(3600, binary.LittleEndian),
+				Uint32(10, binary.LittleEndian),
+				Uint32(40, binary.LittleEndian),
+				Uint32(60, binary.LittleEndian),
+				Uint32(40, binary.LittleEndian),
+				Uint16(2, binary.LittleEndian),
+				Int16(-20, binary.LittleEndian),
+				Uint16(3, binary.LittleEndian),
+				Uint16(4, binary.LittleEndian),
+				Uint32(90, binary.LittleEndian),
+			),
+			Expected: &RepeaterStats{
+				BatteryMillivolts: 4012,
+				TxQueueLen:        1,
+				NoiseFloor:        -110,
+				LastRSSI:          -80,
+				PacketsRecv:       100,
+				PacketsSent:       50,
+				TxAirtimeSecs:     30,
+				UptimeSecs:        3600,
+				SentFlood:         10,
+				SentDirect:        40,
+				RecvFlood:         60,
+				RecvDirect:        40,
+				ErrEvents:         2,
+				LastSNR:           -20,
+				DirectDups:        3,
+				FloodDups:         4,
+				RxAirtimeSecs:     90,
+			},
+		},
+		{
+			Name:     "older firmware",
+			Data:     BytesFrom(Uint16(3700, binary.LittleEndian), Uint16(0, binary.LittleEndian)),
+			Expected: &RepeaterStats{BatteryMillivolts: 3700},
+		},
+		{
+			Name:  "too short",
+			Data:  []byte{1},
+			Error: poop.New("status data is too short"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			status := &Status{StatusData: test.Data}
+			stats, err := status.RepeaterStats()
+			if !checkError(t, err, test.Error) {
+				return
+			}
+			if *stats != *test.Expected {
+				t.Fatalf("expected %s, got %s", describe(test.Expected), describe(stats))
+			}
+		})
+	}
+}
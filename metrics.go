@@ -0,0 +1,343 @@
+package meshcore
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// commandDurationBuckets are the upper bounds, in seconds, of the command
+// latency histogram.
+var commandDurationBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics collects metrics from a Conn and its transport and serves them in
+// the Prometheus text exposition format. Use Conn.SetMetrics to attach it to
+// a connection.
+type Metrics struct {
+	now func() time.Time
+
+	mu            sync.Mutex
+	pending       pendingCommands
+	commands      map[CommandCode]*commandMetrics
+	notifications map[NotificationCode]uint64
+	dropped       map[NotificationCode]uint64
+	received      map[string]uint64
+	sent          map[string]uint64
+	bytesSent     uint64
+	bytesReceived uint64
+	battery       *float64
+	repeaters     map[string]float64
+	rssi          *float64
+	snr           *float64
+}
+
+type commandMetrics struct {
+	count   uint64
+	errors  uint64
+	buckets []uint64
+	sum     float64
+	timed   uint64
+}
+
+// NewMetrics creates an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		now:           time.Now,
+		commands:      map[CommandCode]*commandMetrics{},
+		notifications: map[NotificationCode]uint64{},
+		dropped:       map[NotificationCode]uint64{},
+		received:      map[string]uint64{},
+		sent:          map[string]uint64{},
+		repeaters:     map[string]float64{},
+	}
+}
+
+func (m *Metrics) command(code CommandCode) *commandMetrics {
+	c := m.commands[code]
+	if c == nil {
+		c = &commandMetrics{buckets: make([]uint64, len(commandDurationBuckets))}
+		m.commands[code] = c
+	}
+	return c
+}
+
+func (m *Metrics) observeCommand(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.bytesSent += uint64(len(data))
+
+	code := CommandCode(data[0])
+	m.command(code).count++
+
+	switch code {
+	case CommandSendTxtMsg:
+		m.sent["contact"]++
+	case CommandSendChannelTxtMsg:
+		m.sent["channel"]++
+	}
+
+	m.pending.push(code, m.now())
+}
+
+// observeResponse completes the pending command answered by n.
+func (m *Metrics) observeResponse(n Notification) {
+	p, ok := m.pending.pop(n.NotificationCode())
+	if !ok {
+		return
+	}
+
+	c := m.command(p.code)
+	if _, ok := n.(*ErrNotification); ok {
+		c.errors++
+	}
+
+	d := m.now().Sub(p.at).Seconds()
+	c.sum += d
+	c.timed++
+	for i, le := range commandDurationBuckets {
+		if d <= le {
+			c.buckets[i]++
+		}
+	}
+}
+
+func (m *Metrics) observeNotification(
+	code NotificationCode,
+	data []byte,
+	n Notification,
+	err error,
+) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.bytesReceived += uint64(len(data)) + 1
+	m.notifications[code]++
+
+	if err != nil {
+		return
+	}
+
+	m.observeResponse(n)
+
+	switch t := n.(type) {
+	case *ContactMsgRecvNotification, *ContactMsgRecvV3Notification:
+		m.received["contact"]++
+	case *ChannelMsgRecvNotification, *ChannelMsgRecvV3Notification:
+		m.received["channel"]++
+	case *BatteryVoltageNotification:
+		v := float64(t.Voltage)
+		m.battery = &v
+	case *StatusNotification:
+		if stats, err := t.Status.RepeaterStats(); err == nil {
+			m.repeaters[hex.EncodeToString(t.Status.PubKeyPrefix[:])] = float64(stats.BatteryMillivolts)
+		}
+	case *LogRxDataNotification:
+		m.setSignal(t.LastRSSI, t.LastSNR)
+	case *RawDataNotification:
+		m.setSignal(t.LastRSSI, t.LastSNR)
+	}
+}
+
+func (m *Metrics) setSignal(rssi int8, snr float64) {
+	r := float64(rssi)
+	m.rssi = &r
+	m.snr = &snr
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mw := &metricsWriter{w: bufio.NewWriter(w)}
+
+	mw.header("meshcore_commands_total", "counter", "Commands sent to the device.")
+	for code, c := range sortedByKey(m.commands) {
+		mw.sample("meshcore_commands_total", float64(c.count), "command", codeLabel(code))
+	}
+
+	mw.header("meshcore_command_errors_total", "counter", "Commands the device answered with an error.")
+	for code, c := range sortedByKey(m.commands) {
+		mw.sample("meshcore_command_errors_total", float64(c.errors), "command", codeLabel(code))
+	}
+
+	mw.header("meshcore_command_duration_seconds", "histogram", "Time from sending a command to its response.")
+	for code, c := range sortedByKey(m.commands) {
+		for i, le := range commandDurationBuckets {
+			mw.sample("meshcore_command_duration_seconds_bucket", float64(c.buckets[i]),
+				"command", codeLabel(code), "le", strconv.FormatFloat(le, 'g', -1, 64))
+		}
+		mw.sample("meshcore_command_duration_seconds_bucket", float64(c.timed), "command", codeLabel(code), "le", "+Inf")
+		mw.sample("meshcore_command_duration_seconds_sum", c.sum, "command", codeLabel(code))
+		mw.sample("meshcore_command_duration_seconds_count", float64(c.timed), "command", codeLabel(code))
+	}
+
+	mw.header("meshcore_notifications_total", "counter", "Notifications received from the device.")
+	for code, n := range sortedByKey(m.notifications) {
+		mw.sample("meshcore_notifications_total", float64(n), "notification", codeLabel(code))
+	}
+
+	mw.header("meshcore_notifications_dropped_total", "counter", "Notifications dropped because nothing was subscribed to them.")
+	for code, n := range sortedByKey(m.dropped) {
+		mw.sample("meshcore_notifications_dropped_total", float64(n), "notification", codeLabel(code))
+	}
+
+	mw.header("meshcore_messages_sent_total", "counter", "Text messages sent.")
+	for kind, n := range sortedByKey(m.sent) {
+		mw.sample("meshcore_messages_sent_total", float64(n), "type", kind)
+	}
+
+	mw.header("meshcore_messages_received_total", "counter", "Text messages received.")
+	for kind, n := range sortedByKey(m.received) {
+		mw.sample("meshcore_messages_received_total", float64(n), "type", kind)
+	}
+
+	mw.header("meshcore_transport_sent_bytes_total", "counter", "Bytes of commands written to the transport.")
+	mw.sample("meshcore_transport_sent_bytes_total", float64(m.bytesSent))
+
+	mw.header("meshcore_transport_received_bytes_total", "counter", "Bytes of notifications read from the transport.")
+	mw.sample("meshcore_transport_received_bytes_total", float64(m.bytesReceived))
+
+	if m.battery != nil {
+		mw.header("meshcore_battery_millivolts", "gauge", "Battery voltage of the device.")
+		mw.sample("meshcore_battery_millivolts", *m.battery)
+	}
+
+	if len(m.repeaters) > 0 {
+		mw.header("meshcore_repeater_battery_millivolts", "gauge", "Battery voltage of repeaters from their status.")
+		for prefix, v := range sortedByKey(m.repeaters) {
+			mw.sample("meshcore_repeater_battery_millivolts", v, "repeater", prefix)
+		}
+	}
+
+	if m.rssi != nil {
+		mw.header("meshcore_last_rssi_dbm", "gauge", "RSSI of the last packet received.")
+		mw.sample("meshcore_last_rssi_dbm", *m.rssi)
+		mw.header("meshcore_last_snr_db", "gauge", "SNR of the last packet received.")
+		mw.sample("meshcore_last_snr_db", *m.snr)
+	}
+
+	if err := mw.w.Flush(); err != nil && mw.err == nil {
+		mw.err = err
+	}
+	return mw.n, mw.err
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type metricsWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *metricsWriter) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.w, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
+
+func (w *metricsWriter) header(name, kind, help string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes a sample with the given label names and values, which
+// alternate.
+func (w *metricsWriter) sample(name string, value float64, labels ...string) {
+	w.printf("%s", name)
+	for i := 0; i < len(labels); i += 2 {
+		sep := ","
+		if i == 0 {
+			sep = "{"
+		}
+		w.printf("%s%s=\"%s\"", sep, labels[i], labelEscaper.Replace(labels[i+1]))
+	}
+	if len(labels) > 0 {
+		w.printf("}")
+	}
+	w.printf(" %s\n", strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// codeLabel returns the name of a command or notification code, or its
+// number if it is unknown.
+func codeLabel[C interface {
+	~byte
+	String() string
+}](code C) string {
+	if s := code.String(); s != "" {
+		return s
+	}
+	return strconv.Itoa(int(code))
+}
+
+func sortedByKey[K cmp.Ordered, V any](m map[K]V) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			if !yield(k, m[k]) {
+				return
+			}
+		}
+	}
+}
+
+func (m *Metrics) observeDropped(code NotificationCode) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dropped[code]++
+}
+
+// Middleware returns a Middleware that records the commands and
+// notifications passing through a transport.
+func (m *Metrics) Middleware() Middleware {
+	mw := Middleware{
+		Send: func(next SendFunc) SendFunc {
+			return func(frame []byte) error {
+				m.observeCommand(frame)
+				return next(frame)
+			}
+		},
+	}
+	mw.Recv, mw.frameRecv = frameMiddleware(func(next frameRecvFunc) frameRecvFunc {
+		return func(f *recvFrame) int {
+			n, err := f.notification()
+			m.observeNotification(f.code, f.data, n, err)
+			delivered := next(f)
+			if delivered == 0 {
+				m.observeDropped(f.code)
+			}
+			return delivered
+		}
+	})
+	return mw
+}
+
+// SetMetrics attaches Metrics that record the commands sent on the
+// connection and, if the transport publishes through a NotificationCenter,
+// the notifications it receives. It is shorthand for c.Use(m.Middleware()).
+func (c *Conn) SetMetrics(m *Metrics) {
+	c.Use(m.Middleware())
+}
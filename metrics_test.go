@@ -0,0 +1,89 @@
+package meshcore
+
+import (
+	"encoding/binary"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	now := time.Unix(1000, 0)
+	m.now = func() time.Time { return now }
+
+	controller := DoCommand(func(conn *Conn) {
+		conn.SetMetrics(m)
+
+		if _, err := conn.GetBatteryVoltage(t.Context()); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := conn.SendChannelTextMessage(t.Context(), 0, "hi", TextTypePlain); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	controller.Recv()
+	now = now.Add(20 * time.Millisecond)
+	controller.Notify(NotificationTypeBatteryVoltage, BytesFrom(Uint16(3987, binary.LittleEndian)))
+
+	controller.Recv()
+	now = now.Add(2 * time.Second)
+	controller.Notify(NotificationTypeErr, BytesFrom(Byte(byte(ErrorCodeTableFull))))
+
+	controller.Wait()
+
+	// Nothing is subscribed to these.
+	controller.Notify(NotificationTypeLogRxData, BytesFrom(
+		Byte(0xe6), // snr -26/4
+		Byte(0x9f), // rssi -97
+		Bytes(1, 2, 3),
+	))
+	controller.Notify(NotificationTypeStatus, BytesFrom(
+		Byte(0),
+		Bytes(1, 2, 3, 4, 5, 6),
+		Uint16(4100, binary.LittleEndian),
+	))
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE meshcore_commands_total counter",
+		`meshcore_commands_total{command="SendChannelTxtMsg"} 1`,
+		`meshcore_commands_total{command="GetBatteryVoltage"} 1`,
+		`meshcore_command_errors_total{command="SendChannelTxtMsg"} 1`,
+		`meshcore_command_errors_total{command="GetBatteryVoltage"} 0`,
+		`meshcore_command_duration_seconds_bucket{command="GetBatteryVoltage",le="0.01"} 0`,
+		`meshcore_command_duration_seconds_bucket{command="GetBatteryVoltage",le="0.025"} 1`,
+		`meshcore_command_duration_seconds_bucket{command="SendChannelTxtMsg",le="1"} 0`,
+		`meshcore_command_duration_seconds_bucket{command="SendChannelTxtMsg",le="2.5"} 1`,
+		`meshcore_command_duration_seconds_bucket{command="SendChannelTxtMsg",le="+Inf"} 1`,
+		`meshcore_command_duration_seconds_sum{command="SendChannelTxtMsg"} 2`,
+		`meshcore_command_duration_seconds_count{command="SendChannelTxtMsg"} 1`,
+		`meshcore_notifications_total{notification="BatteryVoltage"} 1`,
+		`meshcore_notifications_total{notification="PushLogRxData"} 1`,
+		`meshcore_notifications_dropped_total{notification="PushLogRxData"} 1`,
+		`meshcore_notifications_dropped_total{notification="PushStatus"} 1`,
+		`meshcore_messages_sent_total{type="channel"} 1`,
+		`meshcore_battery_millivolts 3987`,
+		`meshcore_repeater_battery_millivolts{repeater="010203040506"} 4100`,
+		`meshcore_last_rssi_dbm -97`,
+		`meshcore_last_snr_db -6.5`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, body)
+		}
+	}
+
+	if strings.Contains(body, `meshcore_notifications_dropped_total{notification="BatteryVoltage"}`) {
+		t.Errorf("subscribed notification counted as dropped:\n%s", body)
+	}
+}
@@ -0,0 +1,112 @@
+package meshcore
+
+import "slices"
+
+// SendFunc writes a command frame to the device.
+type SendFunc func(frame []byte) error
+
+// RecvFunc publishes a notification frame from the device and returns the
+// number of subscribers it was delivered to.
+type RecvFunc func(code NotificationCode, data []byte) int
+
+// Middleware intercepts the frames passing through a Transport. Send wraps
+// the command frames written to the device and Recv wraps the notification
+// frames published from it. Either may be nil. A middleware can observe or
+// modify a frame before passing it to next, delay it, or drop it by not
+// calling next at all.
+type Middleware struct {
+	Send func(next SendFunc) SendFunc
+	Recv func(next RecvFunc) RecvFunc
+
+	// frameRecv, if set, is used by NotificationCenter in place of Recv so
+	// that the frame is not decoded again.
+	frameRecv func(next frameRecvFunc) frameRecvFunc
+}
+
+// frameMiddleware returns the Recv and frameRecv of a Middleware that does
+// the same thing, given as frameRecv.
+func frameMiddleware(
+	recv func(next frameRecvFunc) frameRecvFunc,
+) (func(next RecvFunc) RecvFunc, func(next frameRecvFunc) frameRecvFunc) {
+	return func(next RecvFunc) RecvFunc {
+		r := recv(func(f *recvFrame) int {
+			return next(f.code, f.data)
+		})
+		return func(code NotificationCode, data []byte) int {
+			return r(&recvFrame{code: code, data: data})
+		}
+	}, recv
+}
+
+// OnSend returns a Middleware that calls fn with every command sent.
+func OnSend(fn func(code CommandCode, data []byte)) Middleware {
+	return Middleware{
+		Send: func(next SendFunc) SendFunc {
+			return func(frame []byte) error {
+				if len(frame) > 0 {
+					fn(CommandCode(frame[0]), frame[1:])
+				}
+				return next(frame)
+			}
+		},
+	}
+}
+
+// OnRecv returns a Middleware that calls fn with every notification
+// received.
+func OnRecv(fn func(code NotificationCode, data []byte)) Middleware {
+	return Middleware{
+		Recv: func(next RecvFunc) RecvFunc {
+			return func(code NotificationCode, data []byte) int {
+				fn(code, data)
+				return next(code, data)
+			}
+		},
+	}
+}
+
+// middlewareTransport passes the frames written to a Transport through a
+// chain of middleware.
+type middlewareTransport struct {
+	Transport
+	send SendFunc
+}
+
+func (t *middlewareTransport) Write(p []byte) (int, error) {
+	if err := t.send(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (t *middlewareTransport) unwrap() Transport {
+	return t.Transport
+}
+
+// WithMiddleware returns a Transport that passes the frames of tx through
+// mws, where the first middleware is the outermost: it sees commands first
+// and notifications last. Recv middleware is installed into the
+// NotificationCenter that tx publishes through and is ignored if there is
+// none.
+func WithMiddleware(tx Transport, mws ...Middleware) Transport {
+	if nc := notificationCenterOf(tx); nc != nil {
+		nc.use(mws)
+	}
+
+	send := func(frame []byte) error {
+		_, err := tx.Write(frame)
+		return err
+	}
+	for _, mw := range slices.Backward(mws) {
+		if mw.Send != nil {
+			send = mw.Send(send)
+		}
+	}
+	return &middlewareTransport{Transport: tx, send: send}
+}
+
+// Use wraps the connection's transport with mws, outside of any middleware
+// added before. It should be called before the connection is used.
+func (c *Conn) Use(mws ...Middleware) {
+	c.tx = WithMiddleware(c.tx, mws...)
+}
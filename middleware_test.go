@@ -0,0 +1,122 @@
+package meshcore
+
+import (
+	"encoding/binary"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Run("order", func(t *testing.T) {
+		var mu sync.Mutex
+		var trace []string
+		record := func(name string) Middleware {
+			return Middleware{
+				Send: func(next SendFunc) SendFunc {
+					return func(frame []byte) error {
+						mu.Lock()
+						trace = append(trace, "send "+name)
+						mu.Unlock()
+						return next(frame)
+					}
+				},
+				Recv: func(next RecvFunc) RecvFunc {
+					return func(code NotificationCode, data []byte) int {
+						mu.Lock()
+						trace = append(trace, "recv "+name)
+						mu.Unlock()
+						return next(code, data)
+					}
+				},
+			}
+		}
+
+		controller := DoCommand(func(conn *Conn) {
+			conn.Use(record("a"), record("b"))
+			conn.Use(record("c"))
+			if _, err := conn.GetBatteryVoltage(t.Context()); err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		controller.Recv()
+		controller.Notify(NotificationTypeBatteryVoltage, BytesFrom(Uint16(3987, binary.LittleEndian)))
+		controller.Wait()
+
+		expected := []string{"send c", "send a", "send b", "recv b", "recv a", "recv c"}
+		if !slices.Equal(trace, expected) {
+			t.Fatalf("expected %v, got %v", expected, trace)
+		}
+	})
+
+	t.Run("modify", func(t *testing.T) {
+		controller := DoCommand(func(conn *Conn) {
+			conn.Use(Middleware{
+				Recv: func(next RecvFunc) RecvFunc {
+					return func(code NotificationCode, data []byte) int {
+						if code == NotificationTypeBatteryVoltage {
+							data = BytesFrom(Uint16(4100, binary.LittleEndian))
+						}
+						return next(code, data)
+					}
+				},
+			})
+			v, err := conn.GetBatteryVoltage(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != 4100 {
+				t.Fatalf("expected 4100, got %d", v)
+			}
+		})
+
+		controller.Recv()
+		controller.Notify(NotificationTypeBatteryVoltage, BytesFrom(Uint16(3987, binary.LittleEndian)))
+		controller.Wait()
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		m := NewMetrics()
+		var sent [][]byte
+		controller := DoCommand(func(conn *Conn) {
+			conn.SetMetrics(m)
+			dropped := false
+			conn.Use(
+				OnSend(func(code CommandCode, data []byte) {
+					sent = append(sent, append([]byte{byte(code)}, data...))
+				}),
+				Middleware{
+					Recv: func(next RecvFunc) RecvFunc {
+						return func(code NotificationCode, data []byte) int {
+							if !dropped {
+								dropped = true
+								return 0
+							}
+							return next(code, data)
+						}
+					},
+				},
+			)
+			v, err := conn.GetBatteryVoltage(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != 2 {
+				t.Fatalf("expected 2, got %d", v)
+			}
+		})
+
+		controller.Recv()
+		controller.Notify(NotificationTypeBatteryVoltage, BytesFrom(Uint16(1, binary.LittleEndian)))
+		controller.Notify(NotificationTypeBatteryVoltage, BytesFrom(Uint16(2, binary.LittleEndian)))
+		controller.Wait()
+
+		if len(sent) != 1 || CommandCode(sent[0][0]) != CommandGetBatteryVoltage {
+			t.Fatalf("expected one GetBatteryVoltage command, got %v", sent)
+		}
+		if d := m.dropped[NotificationTypeBatteryVoltage]; d != 1 {
+			t.Fatalf("expected 1 dropped notification, got %d", d)
+		}
+	})
+}
@@ -34,8 +34,32 @@ func (s *subscription) publish(notification Notification, error error) {
 type NotificationCenter struct {
 	lck           sync.RWMutex
 	subscriptions map[NotificationCode][]*subscription
+	middleware    []Middleware
+	recv          frameRecvFunc
 }
 
+// recvFrame is a notification frame passing through the Recv middleware. It
+// is decoded at most once, however many middleware look at it.
+type recvFrame struct {
+	code    NotificationCode
+	data    []byte
+	decoded bool
+	n       Notification
+	err     error
+}
+
+func (f *recvFrame) notification() (Notification, error) {
+	if !f.decoded {
+		f.n, f.err = readNotification(f.code, f.data)
+		f.decoded = true
+	}
+	return f.n, f.err
+}
+
+// frameRecvFunc is the RecvFunc of the middleware in this package, which
+// share the decoded notification.
+type frameRecvFunc func(f *recvFrame) int
+
 func NewNotificationCenter() *NotificationCenter {
 	return &NotificationCenter{
 		subscriptions: make(map[NotificationCode][]*subscription),
@@ -95,19 +119,83 @@ func (e *NotificationCenter) Subscribe(
 	}
 }
 
-func (e *NotificationCenter) Publish(code NotificationCode, data []byte) {
+// Publish delivers a notification to its subscribers, after passing it
+// through any Recv middleware, and returns the number of subscribers it was
+// delivered to.
+func (e *NotificationCenter) Publish(code NotificationCode, data []byte) int {
+	e.lck.RLock()
+	recv := e.recv
+	e.lck.RUnlock()
+
+	f := &recvFrame{code: code, data: data}
+	if recv == nil {
+		return e.publish(f)
+	}
+	return recv(f)
+}
+
+func (e *NotificationCenter) publish(f *recvFrame) int {
 	e.lck.RLock()
 	defer e.lck.RUnlock()
 
-	streams := e.subscriptions[code]
+	streams := e.subscriptions[f.code]
 	if len(streams) == 0 {
-		return
+		return 0
 	}
 
-	notification, err := readNotification(code, data)
+	notification, err := f.notification()
 	for _, s := range streams {
 		s.publish(notification, err)
 	}
+	return len(streams)
+}
+
+// use installs the Recv side of mws outside of any middleware installed
+// before, so that they see notifications after it.
+func (e *NotificationCenter) use(mws []Middleware) {
+	e.lck.Lock()
+	defer e.lck.Unlock()
+
+	e.middleware = append(slices.Clone(mws), e.middleware...)
+
+	var recv frameRecvFunc = e.publish
+	for _, mw := range e.middleware {
+		switch {
+		case mw.frameRecv != nil:
+			recv = mw.frameRecv(recv)
+		case mw.Recv != nil:
+			// Other middleware may change the frame, so what it passes on
+			// is decoded again.
+			next := recv
+			r := mw.Recv(func(code NotificationCode, data []byte) int {
+				return next(&recvFrame{code: code, data: data})
+			})
+			recv = func(f *recvFrame) int {
+				return r(f.code, f.data)
+			}
+		}
+	}
+	e.recv = recv
+}
+
+func (e *NotificationCenter) notificationCenter() *NotificationCenter {
+	return e
+}
+
+// notificationCenterOf returns the NotificationCenter that tx publishes
+// through, looking through the wrappers added by Conn, or nil if there is
+// none.
+func notificationCenterOf(tx Transport) *NotificationCenter {
+	for {
+		switch t := tx.(type) {
+		case interface{ notificationCenter() *NotificationCenter }:
+			return t.notificationCenter()
+		case interface{ unwrap() Transport }:
+			tx = t.unwrap()
+		default:
+			return nil
+		}
+	}
 }
 
 func (e *NotificationCenter) Shutdown() {
@@ -1,8 +1,10 @@
 package meshcore
 
 import (
+	"encoding/binary"
 	"errors"
 	"iter"
+	"log/slog"
 	"testing"
 )
 
@@ -32,3 +34,33 @@ func TestShutdown(t *testing.T) {
 		}
 	})
 }
+
+func TestPublishDecodesOnce(t *testing.T) {
+	var seen []Notification
+	observe := Middleware{
+		frameRecv: func(next frameRecvFunc) frameRecvFunc {
+			return func(f *recvFrame) int {
+				n, _ := f.notification()
+				seen = append(seen, n)
+				return next(f)
+			}
+		},
+	}
+
+	nc := NewNotificationCenter()
+	logging := LogMiddleware(&LogOptions{Logger: slog.New(slog.DiscardHandler)})
+	nc.use([]Middleware{observe, logging, observe})
+
+	next, done := iter.Pull2(nc.Subscribe(t.Context(), NotificationTypeBatteryVoltage))
+	defer done()
+
+	go nc.Publish(NotificationTypeBatteryVoltage, BytesFrom(Uint16(3987, binary.LittleEndian)))
+
+	n, err, _ := next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 2 || seen[0] != n || seen[1] != n {
+		t.Fatalf("expected every middleware to see the published notification, got %v", seen)
+	}
+}
@@ -40,6 +40,7 @@ const (
 	NotificationTypeChannelInfo      NotificationCode = 18
 	NotificationTypeSignStart        NotificationCode = 19
 	NotificationTypeSignature        NotificationCode = 20
+	NotificationTypeTuningParams     NotificationCode = 23
 	// Push notifications, can arrive without a corresponding command.
 	NotificationTypeAdvert         NotificationCode = 0x80 // when companion is set to auto add contacts
 	NotificationTypePathUpdated    NotificationCode = 0x81
@@ -78,6 +79,7 @@ var notificationCodeText = map[NotificationCode]string{
 	NotificationTypeChannelInfo:      "ChannelInfo",
 	NotificationTypeSignStart:        "SignStart",
 	NotificationTypeSignature:        "Signature",
+	NotificationTypeTuningParams:     "TuningParams",
 	NotificationTypeAdvert:           "PushAdvert",
 	NotificationTypePathUpdated:      "PushPathUpdated",
 	NotificationTypeSendConfirmed:    "PushSendConfirmed",
@@ -171,6 +173,8 @@ func readNotification(code NotificationCode, data []byte) (Notification, error)
 		return readSignStartNotification(data)
 	case NotificationTypeSignature:
 		return readSignatureNotification(data)
+	case NotificationTypeTuningParams:
+		return readTuningParamsNotification(data)
 	case NotificationTypeAdvert:
 		return readAdvertNotification(data)
 	case NotificationTypePathUpdated:
@@ -467,6 +471,27 @@ func readChannelInfoNotification(data []byte) (*ChannelInfoNotification, error)
 	return &n, nil
 }
 
+type TuningParamsNotification struct {
+	TuningParams TuningParams
+}
+
+func (e *TuningParamsNotification) NotificationCode() NotificationCode {
+	return NotificationTypeTuningParams
+}
+
+//	RESP_CODE_TUNING_PARAMS {
+//	  code: byte,             // constant 23
+//	  rx_delay_base: uint32,  // * 1000
+//	  airtime_factor: uint32  // * 1000
+//	}
+func readTuningParamsNotification(data []byte) (*TuningParamsNotification, error) {
+	var n TuningParamsNotification
+	if err := n.TuningParams.readFrom(bytes.NewReader(data)); err != nil {
+		return nil, poop.Chain(err)
+	}
+	return &n, nil
+}
+
 type SignStartNotification struct {
 	MaxSignDataLen uint32
 }
@@ -0,0 +1,60 @@
+package packet
+
+import (
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha256"
+
+	"github.com/kellegous/poop"
+)
+
+// keySize is the size of the AES-128 key taken from the start of a shared
+// secret. The full secret is used as the MAC key.
+const keySize = 16
+
+// Decrypt verifies the MAC with the shared secret and decrypts the
+// ciphertext. MeshCore encrypts with AES-128 in ECB mode, so the plaintext is
+// padded with zeros to a multiple of the block size.
+func (e *Encrypted) Decrypt(secret []byte) ([]byte, error) {
+	if len(secret) < keySize {
+		return nil, poop.Newf("secret must be at least %d bytes, got %d", keySize, len(secret))
+	}
+
+	if !e.Verify(secret) {
+		return nil, poop.New("invalid mac")
+	}
+
+	if len(e.Ciphertext)%aes.BlockSize != 0 {
+		return nil, poop.Newf("ciphertext is not a multiple of %d bytes", aes.BlockSize)
+	}
+
+	block, err := aes.NewCipher(secret[:keySize])
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	plaintext := make([]byte, len(e.Ciphertext))
+	for i := 0; i < len(e.Ciphertext); i += aes.BlockSize {
+		block.Decrypt(plaintext[i:], e.Ciphertext[i:])
+	}
+	return plaintext, nil
+}
+
+// Verify reports whether the MAC of the ciphertext is valid for the shared
+// secret.
+func (e *Encrypted) Verify(secret []byte) bool {
+	return hmac.Equal(e.MAC[:], mac(secret, e.Ciphertext))
+}
+
+func mac(secret, ciphertext []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write(ciphertext)
+	return h.Sum(nil)[:MACSize]
+}
+
+// ChannelHash returns the hash that identifies the channel with the given
+// secret in group packets.
+func ChannelHash(secret []byte) byte {
+	h := sha256.Sum256(secret)
+	return h[0]
+}
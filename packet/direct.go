@@ -0,0 +1,132 @@
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/kellegous/meshcore"
+	"github.com/kellegous/poop"
+)
+
+// Matches reports whether the packet could have been sent between the two
+// keys, in either direction. Only the first byte of each key is carried by
+// the packet, so a match does not guarantee the packet was sent between them.
+func (d *Direct) Matches(a, b *meshcore.PublicKey) bool {
+	x, y := a.Prefix(1)[0], b.Prefix(1)[0]
+	return (d.SrcHash == x && d.DestHash == y) || (d.SrcHash == y && d.DestHash == x)
+}
+
+// Plaintext decrypts the payload with the secret that key shares with peer.
+// Either node may be the sender. The plaintext is zero padded to a multiple
+// of the AES block size.
+func (d *Direct) Plaintext(key *meshcore.PrivateKey, peer *meshcore.PublicKey) ([]byte, error) {
+	self := key.PublicKey()
+	if !d.Matches(&self, peer) {
+		return nil, poop.Newf("packet from %02x to %02x is not between these keys", d.SrcHash, d.DestHash)
+	}
+
+	secret, err := key.SharedSecret(peer)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	plaintext, err := d.Encrypted.Decrypt(secret)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	return plaintext, nil
+}
+
+// DirectMessage is a decrypted direct text message.
+//
+//	timestamp: uint32,
+//	flags: byte,     // upper 6 bits are the text type, lower 2 the attempt
+//	text: bytes      // zero padded
+type DirectMessage struct {
+	SenderTime time.Time
+	TextType   meshcore.TextType
+	Attempt    byte
+	Text       string
+}
+
+// Decrypt decrypts the message with the secret that key shares with peer.
+// This works for messages sent in either direction, so key may belong to the
+// sender or the recipient.
+func (p *TextMessage) Decrypt(key *meshcore.PrivateKey, peer *meshcore.PublicKey) (*DirectMessage, error) {
+	plaintext, err := p.Plaintext(key, peer)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	if len(plaintext) < 5 {
+		return nil, poop.New("message is too short")
+	}
+
+	text := plaintext[5:]
+	if i := bytes.IndexByte(text, 0); i >= 0 {
+		text = text[:i]
+	}
+
+	return &DirectMessage{
+		SenderTime: time.Unix(int64(binary.LittleEndian.Uint32(plaintext)), 0),
+		TextType:   meshcore.TextType(plaintext[4] >> 2),
+		Attempt:    plaintext[4] & 0x03,
+		Text:       string(text),
+	}, nil
+}
+
+// RequestData is a decrypted request.
+//
+//	timestamp: uint32,
+//	type: byte,
+//	data: bytes      // zero padded
+type RequestData struct {
+	SenderTime time.Time
+	Type       byte
+	Data       []byte
+}
+
+// Decrypt decrypts the request with the secret that key shares with peer.
+func (p *Request) Decrypt(key *meshcore.PrivateKey, peer *meshcore.PublicKey) (*RequestData, error) {
+	plaintext, err := p.Plaintext(key, peer)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	if len(plaintext) < 5 {
+		return nil, poop.New("request is too short")
+	}
+
+	return &RequestData{
+		SenderTime: time.Unix(int64(binary.LittleEndian.Uint32(plaintext)), 0),
+		Type:       plaintext[4],
+		Data:       plaintext[5:],
+	}, nil
+}
+
+// ResponseData is a decrypted response.
+//
+//	tag: uint32,     // matches the tag of the request
+//	data: bytes      // zero padded
+type ResponseData struct {
+	Tag  uint32
+	Data []byte
+}
+
+// Decrypt decrypts the response with the secret that key shares with peer.
+func (p *Response) Decrypt(key *meshcore.PrivateKey, peer *meshcore.PublicKey) (*ResponseData, error) {
+	plaintext, err := p.Plaintext(key, peer)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	if len(plaintext) < 4 {
+		return nil, poop.New("response is too short")
+	}
+
+	return &ResponseData{
+		Tag:  binary.LittleEndian.Uint32(plaintext),
+		Data: plaintext[4:],
+	}, nil
+}
@@ -0,0 +1,169 @@
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/kellegous/meshcore"
+	"github.com/kellegous/meshcore/internal/edwards25519"
+)
+
+func testPrivateKey(t *testing.T, id byte) meshcore.PrivateKey {
+	expanded := edwards25519.Expand(bytes.Repeat([]byte{id}, 32))
+	key, err := meshcore.PrivateKeyFromBytes(expanded[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// The known-answer vectors use the key pairs from RFC 8032 section 7.1 (TEST 1
+// for alice and TEST 2 for bob). No firmware capture was available, so the
+// shared secret and packets were produced with OpenSSL, independently of
+// this package, following the firmware: ed25519_key_exchange for the secret
+// and Utils::encryptThenMAC for the payload.
+const (
+	knownAlicePrivate = "307c83864f2833cb427a2ef1c00a013cfdff2768d980c0a3a520f006904de94f" +
+		"9b4f0afe280b746a778684e75442502057b7473a03f08f96f5a38e9287e01f8f"
+	knownBobPrivate = "68bd9ed75882d52815a97585caf4790a7f6c6b3b7f821c5e259a24b02e502e51" +
+		"4566848291dacaf225cc63deb348da318e2c2e17b00b8160f9ce6bfa0472911d"
+	knownSecret = "5166f24a6918368e2af831a4affadd97af0ac326bdf143596c045967cc00230e"
+	// knownTextMessage is a flood TXT_MSG from alice to bob of "hello, bob" sent
+	// at 1700000000.
+	knownTextMessage = "09003dd70b9b96dc71d0bfe210e60e60da861b59f8f6"
+)
+
+func knownPrivateKey(t *testing.T, s string) meshcore.PrivateKey {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := meshcore.PrivateKeyFromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestTextMessageKnownAnswer(t *testing.T) {
+	alice := knownPrivateKey(t, knownAlicePrivate)
+	bob := knownPrivateKey(t, knownBobPrivate)
+	alicePub, bobPub := alice.PublicKey(), bob.PublicKey()
+
+	if expected := "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"; alicePub.String() != expected {
+		t.Fatalf("expected %s, got %s", expected, alicePub.String())
+	}
+
+	secret, err := bob.SharedSecret(&alicePub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(secret) != knownSecret {
+		t.Fatalf("expected secret %s, got %x", knownSecret, secret)
+	}
+
+	packet, _ := hex.DecodeString(knownTextMessage)
+	p, err := Parse(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, ok := p.Payload.(*TextMessage)
+	if !ok {
+		t.Fatalf("expected *TextMessage, got %T", p.Payload)
+	}
+	if !msg.Matches(&bobPub, &alicePub) {
+		t.Fatal("expected the packet to match bob and alice")
+	}
+
+	m, err := msg.Decrypt(&bob, &alicePub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Text != "hello, bob" || !m.SenderTime.Equal(time.Unix(1700000000, 0)) || m.Attempt != 0 || m.TextType != meshcore.TextTypePlain {
+		t.Fatalf("unexpected message: %+v", m)
+	}
+}
+
+func TestEncryptedDecrypt(t *testing.T) {
+	// The ciphertext is the AES-128 known answer from FIPS-197 appendix C.1
+	// and the MAC is the first two bytes of its HMAC-SHA256 under the same key.
+	secret, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	ciphertext, _ := hex.DecodeString("69c4e0d86a7b0430d8cdb78070b4c55a")
+	e := Encrypted{
+		MAC:        [MACSize]byte{0x3a, 0x43},
+		Ciphertext: ciphertext,
+	}
+
+	plaintext, err := e.Decrypt(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "00112233445566778899aabbccddeeff"; hex.EncodeToString(plaintext) != expected {
+		t.Fatalf("expected %s, got %x", expected, plaintext)
+	}
+
+	e.Ciphertext = ciphertext[:8]
+	if _, err := e.Decrypt(secret); err == nil || err.Error() != "invalid mac" {
+		t.Fatalf("expected invalid mac, got %v", err)
+	}
+}
+
+func TestTextMessageDecrypt(t *testing.T) {
+	alice, bob := testPrivateKey(t, 1), testPrivateKey(t, 2)
+	alicePub, bobPub := alice.PublicKey(), bob.PublicKey()
+
+	secret, err := alice.SharedSecret(&bobPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Unix(1700000000, 0)
+	plaintext := binary.LittleEndian.AppendUint32(nil, uint32(ts.Unix()))
+	plaintext = append(plaintext, byte(meshcore.TextTypePlain)<<2|1)
+	plaintext = append(plaintext, "hello, bob"...)
+	e := encrypt(secret, plaintext)
+
+	packet := []byte{0x0a, 0x00, bobPub.Prefix(1)[0], alicePub.Prefix(1)[0]}
+	packet = append(packet, e.MAC[:]...)
+	packet = append(packet, e.Ciphertext...)
+
+	p, err := Parse(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, ok := p.Payload.(*TextMessage)
+	if !ok {
+		t.Fatalf("expected *TextMessage, got %T", p.Payload)
+	}
+
+	// Both the sender and the recipient can decrypt.
+	for _, tc := range []struct {
+		key  meshcore.PrivateKey
+		peer meshcore.PublicKey
+	}{
+		{bob, alicePub},
+		{alice, bobPub},
+	} {
+		m, err := msg.Decrypt(&tc.key, &tc.peer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Text != "hello, bob" || !m.SenderTime.Equal(ts) || m.Attempt != 1 || m.TextType != meshcore.TextTypePlain {
+			t.Fatalf("unexpected message: %+v", m)
+		}
+	}
+
+	carolKey := testPrivateKey(t, 3)
+	carol := carolKey.PublicKey()
+	if msg.Matches(&alicePub, &carol) {
+		t.Skip("test keys share a hash prefix")
+	}
+	if _, err := msg.Decrypt(&alice, &carol); err == nil {
+		t.Fatal("expected error decrypting with the wrong peer")
+	}
+}
@@ -0,0 +1,90 @@
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"time"
+
+	"github.com/kellegous/meshcore"
+	"github.com/kellegous/poop"
+)
+
+// GroupMessage is a decrypted channel text message.
+//
+//	timestamp: uint32,
+//	flags: byte,      // upper 6 bits are the text type
+//	text: bytes       // "<sender name>: <text>", zero padded
+type GroupMessage struct {
+	Channel    *meshcore.ChannelInfo
+	SenderName string
+	SenderTime time.Time
+	TextType   meshcore.TextType
+	Text       string
+}
+
+// Matches reports whether the packet was sent to the channel. Only the first
+// byte of the channel's hash is carried by the packet, so a match does not
+// guarantee that the channel's secret will decrypt it.
+func (g *Group) Matches(channel *meshcore.ChannelInfo) bool {
+	return g.ChannelHash == ChannelHash(channel.Secret)
+}
+
+// Decrypt decrypts the message with the channel's secret. This does not
+// require the channel to be configured on a device.
+func (p *GroupText) Decrypt(channel *meshcore.ChannelInfo) (*GroupMessage, error) {
+	if !p.Matches(channel) {
+		return nil, poop.Newf("channel %q does not match hash %#02x", channel.Name, p.ChannelHash)
+	}
+
+	plaintext, err := p.Encrypted.Decrypt(channel.Secret)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	if len(plaintext) < 5 {
+		return nil, poop.New("message is too short")
+	}
+
+	m := &GroupMessage{
+		Channel:    channel,
+		SenderTime: time.Unix(int64(binary.LittleEndian.Uint32(plaintext)), 0),
+		TextType:   meshcore.TextType(plaintext[4] >> 2),
+	}
+
+	text := plaintext[5:]
+	if i := bytes.IndexByte(text, 0); i >= 0 {
+		text = text[:i]
+	}
+
+	if name, msg, ok := strings.Cut(string(text), ": "); ok {
+		m.SenderName, m.Text = name, msg
+	} else {
+		m.Text = string(text)
+	}
+
+	return m, nil
+}
+
+// DecryptAny decrypts the message with the first of the channels that both
+// matches the packet's channel hash and has a valid MAC.
+func (p *GroupText) DecryptAny(channels []*meshcore.ChannelInfo) (*GroupMessage, error) {
+	matched := false
+	for _, channel := range channels {
+		if !p.Matches(channel) {
+			continue
+		}
+		matched = true
+
+		if !p.Verify(channel.Secret) {
+			continue
+		}
+
+		return p.Decrypt(channel)
+	}
+
+	if matched {
+		return nil, poop.New("invalid mac")
+	}
+	return nil, poop.Newf("no channel matches hash %#02x", p.ChannelHash)
+}
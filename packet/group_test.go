@@ -0,0 +1,120 @@
+package packet
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kellegous/meshcore"
+)
+
+// encrypt is the inverse of Encrypted.Decrypt.
+func encrypt(secret, plaintext []byte) Encrypted {
+	n := (len(plaintext) + aes.BlockSize - 1) / aes.BlockSize * aes.BlockSize
+	padded := make([]byte, n)
+	copy(padded, plaintext)
+
+	block, err := aes.NewCipher(secret[:keySize])
+	if err != nil {
+		panic(err)
+	}
+
+	var e Encrypted
+	e.Ciphertext = make([]byte, n)
+	for i := 0; i < n; i += aes.BlockSize {
+		block.Encrypt(e.Ciphertext[i:], padded[i:])
+	}
+	copy(e.MAC[:], mac(secret, e.Ciphertext))
+	return e
+}
+
+func groupTextPacket(channel *meshcore.ChannelInfo, ts time.Time, text string) []byte {
+	plaintext := binary.LittleEndian.AppendUint32(nil, uint32(ts.Unix()))
+	plaintext = append(plaintext, 0)
+	plaintext = append(plaintext, text...)
+
+	e := encrypt(channel.Secret, plaintext)
+
+	packet := []byte{0x15, 0x00, ChannelHash(channel.Secret)}
+	packet = append(packet, e.MAC[:]...)
+	return append(packet, e.Ciphertext...)
+}
+
+func TestGroupTextDecrypt(t *testing.T) {
+	public := meshcore.PublicChannel()
+	hashtag, err := meshcore.HashtagChannel("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Unix(1700000000, 0)
+	p, err := Parse(groupTextPacket(hashtag, ts, "alice: hello, world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, ok := p.Payload.(*GroupText)
+	if !ok {
+		t.Fatalf("expected *GroupText, got %T", p.Payload)
+	}
+
+	m, err := g.DecryptAny([]*meshcore.ChannelInfo{public, hashtag})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Channel != hashtag {
+		t.Fatalf("expected channel %s, got %s", hashtag.Name, m.Channel.Name)
+	}
+	if m.SenderName != "alice" || m.Text != "hello, world" {
+		t.Fatalf("expected alice: hello, world, got %s: %s", m.SenderName, m.Text)
+	}
+	if !m.SenderTime.Equal(ts) || m.TextType != meshcore.TextTypePlain {
+		t.Fatalf("unexpected message: %+v", m)
+	}
+
+	expected := fmt.Sprintf("no channel matches hash %#02x", g.ChannelHash)
+	if _, err := g.DecryptAny([]*meshcore.ChannelInfo{public}); err == nil || err.Error() != expected {
+		t.Fatalf("expected %s, got %v", expected, err)
+	}
+
+	g.MAC[0] ^= 0xff
+	if _, err := g.Decrypt(hashtag); err == nil || err.Error() != "invalid mac" {
+		t.Fatalf("expected invalid mac, got %v", err)
+	}
+}
+
+func TestGroupTextKnownAnswer(t *testing.T) {
+	// A flood GRP_TXT on the public channel of "alice: hello, mesh" sent at
+	// 1700000000, produced as described for the direct message vectors.
+	packet, _ := hex.DecodeString("150011010a48f4884524f1fdd7ead13790fca6e1ec4e4e8842f71d9ca8db2915d07520127e")
+
+	p, err := Parse(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, ok := p.Payload.(*GroupText)
+	if !ok {
+		t.Fatalf("expected *GroupText, got %T", p.Payload)
+	}
+
+	// Firmware sends public channel packets with the hash 0x11.
+	if g.ChannelHash != 0x11 {
+		t.Fatalf("expected channel hash 0x11, got %#02x", g.ChannelHash)
+	}
+
+	m, err := g.Decrypt(meshcore.PublicChannel())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.SenderName != "alice" || m.Text != "hello, mesh" {
+		t.Fatalf("expected alice: hello, mesh, got %s: %s", m.SenderName, m.Text)
+	}
+	if !m.SenderTime.Equal(time.Unix(1700000000, 0)) || m.TextType != meshcore.TextTypePlain {
+		t.Fatalf("unexpected message: %+v", m)
+	}
+}
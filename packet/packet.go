@@ -0,0 +1,147 @@
+// Package packet decodes MeshCore packets as they are sent over the air, such
+// as the ones carried by meshcore.LogRxDataNotification.
+package packet
+
+import (
+	"encoding/binary"
+
+	"github.com/kellegous/meshcore"
+	"github.com/kellegous/poop"
+)
+
+const (
+	routeTypeMask    = 0x03
+	payloadTypeShift = 2
+	payloadTypeMask  = 0x0f
+	versionShift     = 6
+
+	// MaxPathSize is the largest path the firmware will accept.
+	MaxPathSize = 64
+)
+
+// RouteType describes how a packet is routed through the mesh.
+type RouteType byte
+
+const (
+	RouteTypeTransportFlood  RouteType = 0x00
+	RouteTypeFlood           RouteType = 0x01
+	RouteTypeDirect          RouteType = 0x02
+	RouteTypeTransportDirect RouteType = 0x03
+)
+
+var routeTypeText = map[RouteType]string{
+	RouteTypeTransportFlood:  "TransportFlood",
+	RouteTypeFlood:           "Flood",
+	RouteTypeDirect:          "Direct",
+	RouteTypeTransportDirect: "TransportDirect",
+}
+
+func (t RouteType) String() string {
+	return routeTypeText[t]
+}
+
+// HasTransportCodes reports whether packets with this route type carry
+// transport codes.
+func (t RouteType) HasTransportCodes() bool {
+	return t == RouteTypeTransportFlood || t == RouteTypeTransportDirect
+}
+
+// IsFlood reports whether packets with this route type are flooded.
+func (t RouteType) IsFlood() bool {
+	return t == RouteTypeFlood || t == RouteTypeTransportFlood
+}
+
+// Packet is a decoded MeshCore packet.
+//
+//	header: byte,              // route type, payload type and version
+//	transport_codes: uint16[2] // only for transport route types
+//	path_len: byte,
+//	path: bytes(path_len),     // one byte hash per hop
+//	payload: bytes             // remainder of packet
+type Packet struct {
+	RouteType   RouteType
+	PayloadType PayloadType
+	Version     byte
+	// TransportCodes is only set when RouteType.HasTransportCodes reports
+	// true.
+	TransportCodes [2]uint16
+	// Path holds the hash of each node the packet has passed through for
+	// flood packets or will pass through for direct packets. For trace
+	// packets, it holds the SNR recorded at each hop instead, see SNRs.
+	Path []byte
+	// RawPayload is the undecoded payload.
+	RawPayload []byte
+	// Payload is the decoded payload.
+	Payload Payload
+}
+
+// Parse decodes a complete packet, such as the Payload of a
+// meshcore.LogRxDataNotification. Payloads that cannot be decoded are reported
+// as an error, while payloads of a type that is not known are returned as
+// *Unknown.
+func Parse(b []byte) (*Packet, error) {
+	if len(b) < 2 {
+		return nil, poop.New("packet is too short")
+	}
+
+	header := b[0]
+	p := &Packet{
+		RouteType:   RouteType(header & routeTypeMask),
+		PayloadType: PayloadType((header >> payloadTypeShift) & payloadTypeMask),
+		Version:     header >> versionShift,
+	}
+	rest := b[1:]
+
+	if p.RouteType.HasTransportCodes() {
+		if len(rest) < 4 {
+			return nil, poop.New("packet is too short")
+		}
+		p.TransportCodes[0] = binary.LittleEndian.Uint16(rest[0:])
+		p.TransportCodes[1] = binary.LittleEndian.Uint16(rest[2:])
+		rest = rest[4:]
+	}
+
+	if len(rest) < 1 {
+		return nil, poop.New("packet is too short")
+	}
+	pathLen := int(rest[0])
+	if pathLen > MaxPathSize {
+		return nil, poop.Newf("path is %d bytes, max is %d", pathLen, MaxPathSize)
+	} else if len(rest) < 1+pathLen {
+		return nil, poop.New("packet is too short")
+	}
+	p.Path = rest[1 : 1+pathLen]
+	p.RawPayload = rest[1+pathLen:]
+
+	var err error
+	p.Payload, err = ParsePayload(p.PayloadType, p.RawPayload)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	return p, nil
+}
+
+// FromLogRxData decodes the packet carried by a LogRxData notification.
+func FromLogRxData(n *meshcore.LogRxDataNotification) (*Packet, error) {
+	return Parse(n.Payload)
+}
+
+// Hops returns the number of hops recorded in the packet's path.
+func (p *Packet) Hops() int {
+	return len(p.Path)
+}
+
+// SNRs returns the SNR recorded at each hop of a trace packet, or nil if the
+// packet is not a trace.
+func (p *Packet) SNRs() []float64 {
+	if p.PayloadType != PayloadTypeTrace {
+		return nil
+	}
+
+	snrs := make([]float64, len(p.Path))
+	for i, b := range p.Path {
+		snrs[i] = float64(int8(b)) / 4
+	}
+	return snrs
+}
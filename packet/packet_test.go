@@ -0,0 +1,187 @@
+package packet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kellegous/meshcore"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		packet   []byte
+		expected *Packet
+	}{
+		{
+			name: "flood txt_msg",
+			packet: []byte{
+				0x09,             // flood, txt_msg, v0
+				0x02, 0xaa, 0xbb, // path
+				0x12, 0x34, // dest, src
+				0x01, 0x02, // mac
+				0x03, 0x04, 0x05,
+			},
+			expected: &Packet{
+				RouteType:   RouteTypeFlood,
+				PayloadType: PayloadTypeTextMessage,
+				Path:        []byte{0xaa, 0xbb},
+				RawPayload:  []byte{0x12, 0x34, 0x01, 0x02, 0x03, 0x04, 0x05},
+				Payload: &TextMessage{Direct{
+					DestHash: 0x12,
+					SrcHash:  0x34,
+					Encrypted: Encrypted{
+						MAC:        [2]byte{0x01, 0x02},
+						Ciphertext: []byte{0x03, 0x04, 0x05},
+					},
+				}},
+			},
+		},
+		{
+			name: "transport flood grp_txt",
+			packet: []byte{
+				0x14,                   // transport flood, grp_txt, v0
+				0x01, 0x00, 0x02, 0x00, // transport codes
+				0x00,       // path
+				0x11,       // channel hash
+				0x22, 0x33, // mac
+				0x44,
+			},
+			expected: &Packet{
+				RouteType:      RouteTypeTransportFlood,
+				PayloadType:    PayloadTypeGroupText,
+				TransportCodes: [2]uint16{1, 2},
+				Path:           []byte{},
+				RawPayload:     []byte{0x11, 0x22, 0x33, 0x44},
+				Payload: &GroupText{Group{
+					ChannelHash: 0x11,
+					Encrypted: Encrypted{
+						MAC:        [2]byte{0x22, 0x33},
+						Ciphertext: []byte{0x44},
+					},
+				}},
+			},
+		},
+		{
+			name: "direct ack",
+			packet: []byte{
+				0x0e,       // direct, ack, v0
+				0x01, 0x07, // path
+				0x78, 0x56, 0x34, 0x12,
+			},
+			expected: &Packet{
+				RouteType:   RouteTypeDirect,
+				PayloadType: PayloadTypeAck,
+				Path:        []byte{0x07},
+				RawPayload:  []byte{0x78, 0x56, 0x34, 0x12},
+				Payload:     &Ack{Checksum: 0x12345678},
+			},
+		},
+		{
+			name: "unknown",
+			packet: []byte{
+				0x71, // flood, type 0x0c, v1
+				0x00,
+				0x01,
+			},
+			expected: &Packet{
+				RouteType:   RouteTypeFlood,
+				PayloadType: 0x0c,
+				Version:     1,
+				Path:        []byte{},
+				RawPayload:  []byte{0x01},
+				Payload:     &Unknown{Type: 0x0c, Data: []byte{0x01}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := Parse(test.packet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(p, test.expected) {
+				t.Fatalf("expected %+v, got %+v", test.expected, p)
+			}
+		})
+	}
+}
+
+func TestParseTrace(t *testing.T) {
+	p, err := Parse([]byte{
+		0x26,             // direct, trace, v0
+		0x02, 0x28, 0xf8, // SNRs
+		0x01, 0x00, 0x00, 0x00, // tag
+		0x02, 0x00, 0x00, 0x00, // auth code
+		0x00,       // flags
+		0xaa, 0xbb, // hashes
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trace, ok := p.Payload.(*Trace)
+	if !ok {
+		t.Fatalf("expected *Trace, got %T", p.Payload)
+	}
+	if trace.Tag != 1 || trace.AuthCode != 2 || !bytes.Equal(trace.Hashes, []byte{0xaa, 0xbb}) {
+		t.Fatalf("unexpected trace: %+v", trace)
+	}
+	if snrs := p.SNRs(); !reflect.DeepEqual(snrs, []float64{10, -2}) {
+		t.Fatalf("expected SNRs [10 -2], got %v", snrs)
+	}
+}
+
+func TestParseAdvert(t *testing.T) {
+	key, err := meshcore.PrivateKeyFromBytes(bytes.Repeat([]byte{0x42}, 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	advert, err := meshcore.NewAdvert(&key, time.Unix(1700000000, 0), &meshcore.AdvertData{
+		Type:  meshcore.ContactTypeRepeater,
+		Flags: meshcore.AdvertFlagName,
+		Name:  "repeater",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := Parse(advert.Packet())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, ok := p.Payload.(*Advert)
+	if !ok {
+		t.Fatalf("expected *Advert, got %T", p.Payload)
+	}
+	if a.Data.Name != "repeater" || !a.Verify() {
+		t.Fatalf("unexpected advert: %+v", a.Advert)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet []byte
+		err    string
+	}{
+		{"empty", nil, "packet is too short"},
+		{"missing transport codes", []byte{0x00, 0x00}, "packet is too short"},
+		{"short path", []byte{0x01, 0x03, 0x00}, "packet is too short"},
+		{"long path", []byte{0x01, 0x41}, "path is 65 bytes, max is 64"},
+		{"short ack", []byte{0x0d, 0x00, 0x01}, "payload is too short"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Parse(test.packet); err == nil || err.Error() != test.err {
+				t.Fatalf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,417 @@
+package packet
+
+import (
+	"encoding/binary"
+
+	"github.com/kellegous/meshcore"
+	"github.com/kellegous/poop"
+)
+
+// MACSize is the size of the truncated MAC that precedes encrypted data.
+const MACSize = 2
+
+// PayloadType identifies the kind of payload carried by a packet.
+type PayloadType byte
+
+const (
+	PayloadTypeRequest     PayloadType = 0x00
+	PayloadTypeResponse    PayloadType = 0x01
+	PayloadTypeTextMessage PayloadType = 0x02
+	PayloadTypeAck         PayloadType = 0x03
+	PayloadTypeAdvert      PayloadType = 0x04
+	PayloadTypeGroupText   PayloadType = 0x05
+	PayloadTypeGroupData   PayloadType = 0x06
+	PayloadTypeAnonRequest PayloadType = 0x07
+	PayloadTypePath        PayloadType = 0x08
+	PayloadTypeTrace       PayloadType = 0x09
+	PayloadTypeMultipart   PayloadType = 0x0A
+	PayloadTypeControl     PayloadType = 0x0B
+	PayloadTypeRawCustom   PayloadType = 0x0F
+)
+
+var payloadTypeText = map[PayloadType]string{
+	PayloadTypeRequest:     "Req",
+	PayloadTypeResponse:    "Response",
+	PayloadTypeTextMessage: "TxtMsg",
+	PayloadTypeAck:         "Ack",
+	PayloadTypeAdvert:      "Advert",
+	PayloadTypeGroupText:   "GrpTxt",
+	PayloadTypeGroupData:   "GrpData",
+	PayloadTypeAnonRequest: "AnonReq",
+	PayloadTypePath:        "Path",
+	PayloadTypeTrace:       "Trace",
+	PayloadTypeMultipart:   "Multipart",
+	PayloadTypeControl:     "Control",
+	PayloadTypeRawCustom:   "RawCustom",
+}
+
+func (t PayloadType) String() string {
+	return payloadTypeText[t]
+}
+
+// Payload is a decoded packet payload. The concrete type is determined by the
+// PayloadType.
+type Payload interface {
+	PayloadType() PayloadType
+}
+
+// ParsePayload decodes a payload of the given type. Payloads of a type that
+// is not known are returned as *Unknown.
+func ParsePayload(t PayloadType, b []byte) (Payload, error) {
+	switch t {
+	case PayloadTypeRequest:
+		d, err := parseDirect(b)
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+		return &Request{d}, nil
+	case PayloadTypeResponse:
+		d, err := parseDirect(b)
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+		return &Response{d}, nil
+	case PayloadTypeTextMessage:
+		d, err := parseDirect(b)
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+		return &TextMessage{d}, nil
+	case PayloadTypePath:
+		d, err := parseDirect(b)
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+		return &Path{d}, nil
+	case PayloadTypeAck:
+		return parseAck(b)
+	case PayloadTypeAdvert:
+		advert, err := meshcore.ParseAdvert(b)
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+		return &Advert{advert}, nil
+	case PayloadTypeGroupText:
+		g, err := parseGroup(b)
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+		return &GroupText{g}, nil
+	case PayloadTypeGroupData:
+		g, err := parseGroup(b)
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+		return &GroupData{g}, nil
+	case PayloadTypeAnonRequest:
+		return parseAnonRequest(b)
+	case PayloadTypeTrace:
+		return parseTrace(b)
+	case PayloadTypeMultipart:
+		return parseMultipart(b)
+	case PayloadTypeControl:
+		return parseControl(b)
+	case PayloadTypeRawCustom:
+		return &RawCustom{Data: b}, nil
+	}
+	return &Unknown{Type: t, Data: b}, nil
+}
+
+// Encrypted is data encrypted with a shared secret, preceded by a truncated
+// MAC of the ciphertext.
+type Encrypted struct {
+	MAC        [MACSize]byte
+	Ciphertext []byte
+}
+
+func parseEncrypted(b []byte) (Encrypted, error) {
+	var e Encrypted
+	if len(b) < MACSize {
+		return e, poop.New("payload is too short")
+	}
+	copy(e.MAC[:], b)
+	e.Ciphertext = b[MACSize:]
+	return e, nil
+}
+
+// Direct is the common layout of payloads sent between two nodes, which
+// are identified by the first byte of their public keys.
+//
+//	dest_hash: byte,
+//	src_hash: byte,
+//	mac: bytes(2),
+//	ciphertext: bytes   // remainder of payload
+type Direct struct {
+	DestHash byte
+	SrcHash  byte
+	Encrypted
+}
+
+func parseDirect(b []byte) (Direct, error) {
+	var d Direct
+	if len(b) < 2 {
+		return d, poop.New("payload is too short")
+	}
+	d.DestHash, d.SrcHash = b[0], b[1]
+
+	var err error
+	d.Encrypted, err = parseEncrypted(b[2:])
+	if err != nil {
+		return d, poop.Chain(err)
+	}
+	return d, nil
+}
+
+// Request is a request sent to a node, such as a login or a status request.
+type Request struct {
+	Direct
+}
+
+func (p *Request) PayloadType() PayloadType {
+	return PayloadTypeRequest
+}
+
+// Response is a node's response to a Request or AnonRequest.
+type Response struct {
+	Direct
+}
+
+func (p *Response) PayloadType() PayloadType {
+	return PayloadTypeResponse
+}
+
+// TextMessage is a direct text message.
+type TextMessage struct {
+	Direct
+}
+
+func (p *TextMessage) PayloadType() PayloadType {
+	return PayloadTypeTextMessage
+}
+
+// Path returns a path to the sender of a flood packet so that replies can be
+// sent direct.
+type Path struct {
+	Direct
+}
+
+func (p *Path) PayloadType() PayloadType {
+	return PayloadTypePath
+}
+
+// Ack acknowledges a message. The checksum is derived from the message, its
+// timestamp and the sender's public key.
+type Ack struct {
+	Checksum uint32
+}
+
+func (p *Ack) PayloadType() PayloadType {
+	return PayloadTypeAck
+}
+
+func parseAck(b []byte) (*Ack, error) {
+	if len(b) < 4 {
+		return nil, poop.New("payload is too short")
+	}
+	return &Ack{Checksum: binary.LittleEndian.Uint32(b)}, nil
+}
+
+// Advert is a node advertising its identity.
+type Advert struct {
+	*meshcore.Advert
+}
+
+func (p *Advert) PayloadType() PayloadType {
+	return PayloadTypeAdvert
+}
+
+// Group is the common layout of payloads sent to a channel.
+//
+//	channel_hash: byte,  // first byte of sha256 of the channel secret
+//	mac: bytes(2),
+//	ciphertext: bytes    // remainder of payload
+type Group struct {
+	ChannelHash byte
+	Encrypted
+}
+
+func parseGroup(b []byte) (Group, error) {
+	var g Group
+	if len(b) < 1 {
+		return g, poop.New("payload is too short")
+	}
+	g.ChannelHash = b[0]
+
+	var err error
+	g.Encrypted, err = parseEncrypted(b[1:])
+	if err != nil {
+		return g, poop.Chain(err)
+	}
+	return g, nil
+}
+
+// GroupText is a text message sent to a channel.
+type GroupText struct {
+	Group
+}
+
+func (p *GroupText) PayloadType() PayloadType {
+	return PayloadTypeGroupText
+}
+
+// GroupData is binary data sent to a channel.
+type GroupData struct {
+	Group
+}
+
+func (p *GroupData) PayloadType() PayloadType {
+	return PayloadTypeGroupData
+}
+
+// AnonRequest is a request from a node the destination may not know, so it
+// carries the sender's full public key.
+//
+//	dest_hash: byte,
+//	sender_key: bytes(32),
+//	mac: bytes(2),
+//	ciphertext: bytes   // remainder of payload
+type AnonRequest struct {
+	DestHash  byte
+	SenderKey meshcore.PublicKey
+	Encrypted
+}
+
+func (p *AnonRequest) PayloadType() PayloadType {
+	return PayloadTypeAnonRequest
+}
+
+func parseAnonRequest(b []byte) (*AnonRequest, error) {
+	if len(b) < 33 {
+		return nil, poop.New("payload is too short")
+	}
+
+	key, err := meshcore.PublicKeyFromBytes(b[1:33])
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	p := &AnonRequest{
+		DestHash:  b[0],
+		SenderKey: key,
+	}
+	p.Encrypted, err = parseEncrypted(b[33:])
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	return p, nil
+}
+
+// Trace traces a route through the mesh. The SNR at each hop is recorded in
+// the packet's path, see Packet.SNRs.
+//
+//	tag: uint32,
+//	auth_code: uint32,
+//	flags: byte,
+//	hashes: bytes   // remainder of payload, the route to trace
+type Trace struct {
+	Tag      uint32
+	AuthCode uint32
+	Flags    byte
+	Hashes   []byte
+}
+
+func (p *Trace) PayloadType() PayloadType {
+	return PayloadTypeTrace
+}
+
+func parseTrace(b []byte) (*Trace, error) {
+	if len(b) < 9 {
+		return nil, poop.New("payload is too short")
+	}
+	return &Trace{
+		Tag:      binary.LittleEndian.Uint32(b[0:]),
+		AuthCode: binary.LittleEndian.Uint32(b[4:]),
+		Flags:    b[8],
+		Hashes:   b[9:],
+	}, nil
+}
+
+// Multipart is one part of a payload that was split across several packets.
+//
+//	header: byte,   // upper nibble is remaining parts, lower is inner type
+//	data: bytes     // remainder of payload
+type Multipart struct {
+	Remaining byte
+	Type      PayloadType
+	Data      []byte
+}
+
+func (p *Multipart) PayloadType() PayloadType {
+	return PayloadTypeMultipart
+}
+
+func parseMultipart(b []byte) (*Multipart, error) {
+	if len(b) < 1 {
+		return nil, poop.New("payload is too short")
+	}
+	return &Multipart{
+		Remaining: b[0] >> 4,
+		Type:      PayloadType(b[0] & 0x0f),
+		Data:      b[1:],
+	}, nil
+}
+
+// Control is a control message, such as a discovery request, that is not
+// retransmitted.
+//
+//	flags: byte,   // upper nibble is the sub type
+//	data: bytes    // remainder of payload
+type Control struct {
+	Flags byte
+	Data  []byte
+}
+
+func (p *Control) PayloadType() PayloadType {
+	return PayloadTypeControl
+}
+
+// SubType returns the kind of control message.
+func (p *Control) SubType() byte {
+	return p.Flags >> 4
+}
+
+func parseControl(b []byte) (*Control, error) {
+	if len(b) < 1 {
+		return nil, poop.New("payload is too short")
+	}
+	return &Control{
+		Flags: b[0],
+		Data:  b[1:],
+	}, nil
+}
+
+// RawCustom is application defined data. The companion firmware pushes these
+// payloads to clients as meshcore.RawDataNotification.
+type RawCustom struct {
+	Data []byte
+}
+
+func (p *RawCustom) PayloadType() PayloadType {
+	return PayloadTypeRawCustom
+}
+
+// FromRawData returns the payload carried by a RawData notification. Unlike
+// LogRxData, only the payload of the packet is pushed.
+func FromRawData(n *meshcore.RawDataNotification) *RawCustom {
+	return &RawCustom{Data: n.Payload}
+}
+
+// Unknown is a payload of a type this package does not know how to decode.
+type Unknown struct {
+	Type PayloadType
+	Data []byte
+}
+
+func (p *Unknown) PayloadType() PayloadType {
+	return p.Type
+}
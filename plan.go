@@ -0,0 +1,68 @@
+package meshcore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kellegous/poop"
+)
+
+// Change is a single change to a device.
+type Change struct {
+	// Description is a human readable description of the change.
+	Description string
+
+	apply func(ctx context.Context, conn *Conn) error
+}
+
+func (c *Change) String() string {
+	return c.Description
+}
+
+// Plan is an ordered set of changes to a device, such as those computed by
+// Config.Plan and Restore.
+type Plan struct {
+	Changes []*Change
+}
+
+func (p *Plan) add(
+	apply func(ctx context.Context, conn *Conn) error,
+	format string,
+	args ...any,
+) {
+	p.Changes = append(p.Changes, &Change{
+		Description: fmt.Sprintf(format, args...),
+		apply:       apply,
+	})
+}
+
+// IsEmpty reports whether the plan has no changes.
+func (p *Plan) IsEmpty() bool {
+	return len(p.Changes) == 0
+}
+
+// String returns the plan with one change per line.
+func (p *Plan) String() string {
+	if p.IsEmpty() {
+		return "no changes\n"
+	}
+
+	var b strings.Builder
+	for _, change := range p.Changes {
+		b.WriteString("  ")
+		b.WriteString(change.Description)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Apply applies the changes in order, stopping at the first one that fails.
+func (p *Plan) Apply(ctx context.Context, conn *Conn) error {
+	for _, change := range p.Changes {
+		if err := change.apply(ctx, conn); err != nil {
+			return poop.ChainWithf(err, "%s", change.Description)
+		}
+	}
+	return nil
+}
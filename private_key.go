@@ -0,0 +1,72 @@
+package meshcore
+
+import (
+	"crypto/ecdh"
+
+	"github.com/kellegous/poop"
+
+	"github.com/kellegous/meshcore/internal/edwards25519"
+)
+
+// PrivateKey is an ed25519 private key in the 64 byte layout used by MeshCore
+// firmware: the clamped scalar followed by the signing prefix. This is the
+// layout returned by Conn.ExportPrivateKey and accepted by
+// Conn.ImportPrivateKey.
+type PrivateKey struct {
+	key [64]byte
+	pub [32]byte
+}
+
+func newPrivateKey(key [64]byte) PrivateKey {
+	return PrivateKey{key: key, pub: edwards25519.PublicKey(&key)}
+}
+
+// PrivateKeyFromBytes creates a PrivateKey from its 64 byte representation.
+func PrivateKeyFromBytes(b []byte) (PrivateKey, error) {
+	var key [64]byte
+	if len(b) != len(key) {
+		return PrivateKey{}, poop.Newf("private key must be %d bytes, got %d", len(key), len(b))
+	}
+	copy(key[:], b)
+	return newPrivateKey(key), nil
+}
+
+func (k *PrivateKey) Bytes() []byte {
+	return k.key[:]
+}
+
+// PublicKey returns the public key for this private key.
+func (k *PrivateKey) PublicKey() PublicKey {
+	return PublicKey{key: k.pub}
+}
+
+// Sign signs data with this private key.
+func (k *PrivateKey) Sign(data []byte) []byte {
+	sig := edwards25519.Sign(&k.key, &k.pub, data)
+	return sig[:]
+}
+
+// X25519 converts the key to the equivalent X25519 private key.
+func (k *PrivateKey) X25519() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().NewPrivateKey(k.key[:32])
+}
+
+// SharedSecret derives the secret this key shares with peer. MeshCore uses
+// it to encrypt and authenticate the packets exchanged between two nodes.
+func (k *PrivateKey) SharedSecret(peer *PublicKey) ([]byte, error) {
+	priv, err := k.X25519()
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	pub, err := peer.X25519()
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	secret, err := priv.ECDH(pub)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	return secret, nil
+}
@@ -0,0 +1,69 @@
+package meshcore
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/kellegous/meshcore/internal/edwards25519"
+)
+
+func fakePrivateKey(t *testing.T, id byte) (PrivateKey, ed25519.PrivateKey) {
+	seed := bytes.Repeat([]byte{id}, ed25519.SeedSize)
+	expanded := edwards25519.Expand(seed)
+	key, err := PrivateKeyFromBytes(expanded[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, ed25519.NewKeyFromSeed(seed)
+}
+
+func TestPrivateKey(t *testing.T) {
+	key, std := fakePrivateKey(t, 42)
+
+	pub := key.PublicKey()
+	if !bytes.Equal(pub.Bytes(), std.Public().(ed25519.PublicKey)) {
+		t.Fatalf("expected %x, got %x", std.Public(), pub.Bytes())
+	}
+
+	data := []byte("Hello, world!")
+	sig := key.Sign(data)
+	if !pub.Verify(data, sig) {
+		t.Fatal("expected signature to verify")
+	}
+
+	if _, err := PrivateKeyFromBytes(make([]byte, 32)); err == nil || err.Error() != "private key must be 64 bytes, got 32" {
+		t.Fatalf("expected error: private key must be 64 bytes, got 32, got %v", err)
+	}
+}
+
+func TestSharedSecret(t *testing.T) {
+	alice, _ := fakePrivateKey(t, 1)
+	bob, _ := fakePrivateKey(t, 2)
+	alicePub, bobPub := alice.PublicKey(), bob.PublicKey()
+
+	// The converted private key must correspond to the converted public key.
+	priv, err := alice.X25519()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := alicePub.X25519()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !priv.PublicKey().Equal(pub) {
+		t.Fatalf("expected %x, got %x", priv.PublicKey().Bytes(), pub.Bytes())
+	}
+
+	a, err := alice.SharedSecret(&bobPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := bob.SharedSecret(&alicePub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, b) || len(a) != 32 {
+		t.Fatalf("expected matching 32 byte secrets, got %x and %x", a, b)
+	}
+}
@@ -1,17 +1,40 @@
 package meshcore
 
 import (
+	"crypto/ecdh"
+	"crypto/ed25519"
 	"encoding/hex"
 	"encoding/json"
 	"io"
 
 	"github.com/kellegous/poop"
+
+	"github.com/kellegous/meshcore/internal/edwards25519"
 )
 
 type PublicKey struct {
 	key [32]byte
 }
 
+// PublicKeyFromBytes creates a PublicKey from its 32 byte representation.
+func PublicKeyFromBytes(b []byte) (PublicKey, error) {
+	var k PublicKey
+	if len(b) != len(k.key) {
+		return k, poop.Newf("public key must be %d bytes, got %d", len(k.key), len(b))
+	}
+	copy(k.key[:], b)
+	return k, nil
+}
+
+// ParsePublicKey parses a hex encoded public key.
+func ParsePublicKey(s string) (PublicKey, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return PublicKey{}, poop.Chain(err)
+	}
+	return PublicKeyFromBytes(b)
+}
+
 func (k *PublicKey) String() string {
 	return hex.EncodeToString(k.key[:])
 }
@@ -49,3 +72,42 @@ func (k *PublicKey) MarshalJSON() ([]byte, error) {
 	s := hex.EncodeToString(k.key[:])
 	return json.Marshal(s)
 }
+
+func (k *PublicKey) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return poop.Chain(err)
+	}
+
+	key, err := ParsePublicKey(s)
+	if err != nil {
+		return poop.Chain(err)
+	}
+	*k = key
+	return nil
+}
+
+// Ed25519 returns the key as an ed25519.PublicKey.
+func (k *PublicKey) Ed25519() ed25519.PublicKey {
+	key := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	copy(key, k.key[:])
+	return key
+}
+
+// X25519 converts the key to the equivalent X25519 public key, which is used
+// to derive the secret shared with another node.
+func (k *PublicKey) X25519() (*ecdh.PublicKey, error) {
+	u, err := edwards25519.MontgomeryU(&k.key)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	return ecdh.X25519().NewPublicKey(u[:])
+}
+
+// Verify reports whether sig is a valid signature of data by this key.
+func (k *PublicKey) Verify(data []byte, sig []byte) bool {
+	if len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(k.key[:], data, sig)
+}
@@ -0,0 +1,80 @@
+package meshcore
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+)
+
+func TestParsePublicKey(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Input         string
+		Expected      PublicKey
+		ExpectedError string
+	}{
+		{
+			Name:     "success",
+			Input:    "2a00000000000000000000000000000000000000000000000000000000000000",
+			Expected: fakePublicKey(42),
+		},
+		{
+			Name:          "too short",
+			Input:         "2a00",
+			ExpectedError: "public key must be 32 bytes, got 2",
+		},
+		{
+			Name:          "not hex",
+			Input:         "zz",
+			ExpectedError: "encoding/hex: invalid byte: U+007A 'z'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			key, err := ParsePublicKey(test.Input)
+			if test.ExpectedError != "" {
+				if err == nil || err.Error() != test.ExpectedError {
+					t.Fatalf("expected error %q, got %v", test.ExpectedError, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if key != test.Expected {
+				t.Fatalf("expected %s, got %s", test.Expected.String(), key.String())
+			}
+		})
+	}
+}
+
+func TestPublicKeyVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := PublicKeyFromBytes(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(key.Ed25519(), pub) {
+		t.Fatalf("expected %x, got %x", pub, key.Ed25519())
+	}
+
+	data := []byte("Hello, world!")
+	sig := ed25519.Sign(priv, data)
+
+	if !key.Verify(data, sig) {
+		t.Fatal("expected signature to verify")
+	}
+
+	if key.Verify([]byte("Goodbye, world!"), sig) {
+		t.Fatal("expected signature over other data to fail")
+	}
+
+	if key.Verify(data, sig[:32]) {
+		t.Fatal("expected short signature to fail")
+	}
+}
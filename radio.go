@@ -0,0 +1,164 @@
+package meshcore
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/kellegous/poop"
+)
+
+// RadioParams are the LoRa parameters of a device's radio.
+type RadioParams struct {
+	// Freq is the frequency in MHz.
+	Freq float64 `json:"freq"`
+	// Bw is the bandwidth in kHz.
+	Bw float64 `json:"bw"`
+	// Sf is the spreading factor.
+	Sf byte `json:"sf"`
+	// Cr is the coding rate, as the denominator of 4/Cr.
+	Cr byte `json:"cr"`
+}
+
+func (r RadioParams) String() string {
+	return fmt.Sprintf("%.3f MHz, BW %g kHz, SF%d, CR%d", r.Freq, r.Bw, r.Sf, r.Cr)
+}
+
+// RadioBand is a range of frequencies, in MHz, where LoRa radios may operate.
+type RadioBand struct {
+	Name string
+	Min  float64
+	Max  float64
+}
+
+// Contains reports whether the frequency, in MHz, is within the band.
+func (b *RadioBand) Contains(freq float64) bool {
+	return freq >= b.Min && freq <= b.Max
+}
+
+// RadioBands are the sub-GHz ISM bands supported by MeshCore radios.
+var RadioBands = []RadioBand{
+	{Name: "433 MHz", Min: 433.05, Max: 434.79},
+	{Name: "470 MHz", Min: 470, Max: 510},
+	{Name: "868 MHz", Min: 863, Max: 870},
+	{Name: "915 MHz", Min: 902, Max: 928},
+}
+
+// RadioBandwidths are the bandwidths, in kHz, supported by LoRa radios.
+var RadioBandwidths = []float64{7.8, 10.4, 15.6, 20.8, 31.25, 41.7, 62.5, 125, 250, 500}
+
+// Band returns the band containing the frequency, or nil if there is none.
+func (r *RadioParams) Band() *RadioBand {
+	for i := range RadioBands {
+		if RadioBands[i].Contains(r.Freq) {
+			return &RadioBands[i]
+		}
+	}
+	return nil
+}
+
+// Validate checks that the parameters are ones a radio can use.
+func (r *RadioParams) Validate() error {
+	if r.Band() == nil {
+		return poop.Newf("frequency %.3f MHz is not in a supported band", r.Freq)
+	}
+
+	if !isRadioBandwidth(r.Bw) {
+		return poop.Newf("bandwidth %g kHz is not supported", r.Bw)
+	}
+
+	if r.Sf < 5 || r.Sf > 12 {
+		return poop.Newf("spreading factor must be 5 to 12, got %d", r.Sf)
+	}
+
+	if r.Cr < 5 || r.Cr > 8 {
+		return poop.Newf("coding rate must be 5 to 8, got %d", r.Cr)
+	}
+
+	return nil
+}
+
+// isRadioBandwidth reports whether bw is one of RadioBandwidths. Devices
+// report bandwidth in Hz, so the odd bandwidths like 41.7 kHz are matched to
+// within 0.1 kHz.
+func isRadioBandwidth(bw float64) bool {
+	for _, b := range RadioBandwidths {
+		if math.Abs(b-bw) < 0.1 {
+			return true
+		}
+	}
+	return false
+}
+
+// Preset returns the preset with these parameters, or nil if there is none.
+func (r *RadioParams) Preset() *RadioPreset {
+	for i := range RadioPresets {
+		if RadioPresets[i].Params == *r {
+			return &RadioPresets[i]
+		}
+	}
+	return nil
+}
+
+// RadioParams returns the device's radio parameters.
+func (s *SelfInfo) RadioParams() RadioParams {
+	return RadioParams{
+		Freq: s.RadioFreq,
+		Bw:   s.RadioBw,
+		Sf:   s.RadioSf,
+		Cr:   s.RadioCr,
+	}
+}
+
+// SetRadioParams sets the device's radio parameters.
+func (s *SelfInfo) SetRadioParams(r RadioParams) {
+	s.RadioFreq = r.Freq
+	s.RadioBw = r.Bw
+	s.RadioSf = r.Sf
+	s.RadioCr = r.Cr
+}
+
+// ValidateTxPower checks that the power, in dBm, does not exceed the
+// device's maximum.
+func (s *SelfInfo) ValidateTxPower(power byte) error {
+	if s.MaxTxPower != 0 && power > s.MaxTxPower {
+		return poop.Newf("tx power %d dBm exceeds the device's max of %d dBm", power, s.MaxTxPower)
+	}
+	return nil
+}
+
+// RadioPreset is a named set of radio parameters used by a regional mesh.
+type RadioPreset struct {
+	Name   string
+	Params RadioParams
+}
+
+// RadioPresets are the presets offered by the MeshCore apps.
+var RadioPresets = []RadioPreset{
+	{Name: "Australia", Params: RadioParams{Freq: 915.8, Bw: 250, Sf: 10, Cr: 5}},
+	{Name: "Australia (Narrow)", Params: RadioParams{Freq: 916.575, Bw: 62.5, Sf: 7, Cr: 8}},
+	{Name: "Australia: SA, WA", Params: RadioParams{Freq: 923.125, Bw: 62.5, Sf: 8, Cr: 8}},
+	{Name: "Australia: QLD", Params: RadioParams{Freq: 923.125, Bw: 62.5, Sf: 8, Cr: 5}},
+	{Name: "Czech Republic (Narrow)", Params: RadioParams{Freq: 869.432, Bw: 62.5, Sf: 7, Cr: 5}},
+	{Name: "EU 433MHz (Long Range)", Params: RadioParams{Freq: 433.65, Bw: 250, Sf: 11, Cr: 5}},
+	{Name: "EU/UK (Long Range)", Params: RadioParams{Freq: 869.525, Bw: 250, Sf: 11, Cr: 5}},
+	{Name: "EU/UK (Medium Range)", Params: RadioParams{Freq: 869.525, Bw: 250, Sf: 10, Cr: 5}},
+	{Name: "EU/UK (Narrow)", Params: RadioParams{Freq: 869.618, Bw: 62.5, Sf: 8, Cr: 8}},
+	{Name: "New Zealand", Params: RadioParams{Freq: 917.375, Bw: 250, Sf: 11, Cr: 5}},
+	{Name: "New Zealand (Narrow)", Params: RadioParams{Freq: 917.375, Bw: 62.5, Sf: 7, Cr: 5}},
+	{Name: "Portugal 433", Params: RadioParams{Freq: 433.375, Bw: 62.5, Sf: 9, Cr: 6}},
+	{Name: "Portugal 868", Params: RadioParams{Freq: 869.618, Bw: 62.5, Sf: 7, Cr: 6}},
+	{Name: "Switzerland", Params: RadioParams{Freq: 869.618, Bw: 62.5, Sf: 8, Cr: 8}},
+	{Name: "USA/Canada (Recommended)", Params: RadioParams{Freq: 910.525, Bw: 62.5, Sf: 7, Cr: 5}},
+	{Name: "Vietnam", Params: RadioParams{Freq: 920.25, Bw: 250, Sf: 11, Cr: 5}},
+}
+
+// FindRadioPreset returns the preset with the given name, ignoring case.
+func FindRadioPreset(name string) (*RadioPreset, error) {
+	for i := range RadioPresets {
+		if strings.EqualFold(RadioPresets[i].Name, name) {
+			return &RadioPresets[i], nil
+		}
+	}
+	return nil, poop.Newf("unknown radio preset: %q", name)
+}
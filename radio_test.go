@@ -0,0 +1,111 @@
+package meshcore
+
+import (
+	"testing"
+)
+
+func TestRadioParamsValidate(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Params RadioParams
+		Error  string
+	}{
+		{
+			Name:   "valid",
+			Params: RadioParams{Freq: 910.525, Bw: 62.5, Sf: 7, Cr: 5},
+		},
+		{
+			Name:   "odd bandwidth reported in hz",
+			Params: RadioParams{Freq: 869.525, Bw: 41.666, Sf: 7, Cr: 5},
+		},
+		{
+			Name:   "frequency typo",
+			Params: RadioParams{Freq: 9105.25, Bw: 62.5, Sf: 7, Cr: 5},
+			Error:  "frequency 9105.250 MHz is not in a supported band",
+		},
+		{
+			Name:   "bandwidth",
+			Params: RadioParams{Freq: 910.525, Bw: 62, Sf: 7, Cr: 5},
+			Error:  "bandwidth 62 kHz is not supported",
+		},
+		{
+			Name:   "spreading factor",
+			Params: RadioParams{Freq: 910.525, Bw: 62.5, Sf: 4, Cr: 5},
+			Error:  "spreading factor must be 5 to 12, got 4",
+		},
+		{
+			Name:   "coding rate",
+			Params: RadioParams{Freq: 910.525, Bw: 62.5, Sf: 7, Cr: 9},
+			Error:  "coding rate must be 5 to 8, got 9",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := test.Params.Validate()
+			if test.Error == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.Error {
+				t.Fatalf("expected error %q, got %v", test.Error, err)
+			}
+		})
+	}
+}
+
+func TestRadioPresets(t *testing.T) {
+	names := map[string]bool{}
+	for _, preset := range RadioPresets {
+		if err := preset.Params.Validate(); err != nil {
+			t.Fatalf("%s: %v", preset.Name, err)
+		}
+		if names[preset.Name] {
+			t.Fatalf("duplicate preset: %s", preset.Name)
+		}
+		names[preset.Name] = true
+	}
+
+	preset, err := FindRadioPreset("eu/uk (narrow)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preset.Params != (RadioParams{Freq: 869.618, Bw: 62.5, Sf: 8, Cr: 8}) {
+		t.Fatalf("unexpected params: %s", preset.Params)
+	}
+
+	if _, err := FindRadioPreset("Atlantis"); err == nil || err.Error() != `unknown radio preset: "Atlantis"` {
+		t.Fatalf("expected unknown preset error, got %v", err)
+	}
+}
+
+func TestSelfInfoRadioParams(t *testing.T) {
+	preset, err := FindRadioPreset("USA/Canada (Recommended)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var info SelfInfo
+	info.SetRadioParams(preset.Params)
+	if info.RadioFreq != 910.525 || info.RadioBw != 62.5 || info.RadioSf != 7 || info.RadioCr != 5 {
+		t.Fatalf("unexpected self info: %s", describe(info))
+	}
+
+	params := info.RadioParams()
+	if params != preset.Params {
+		t.Fatalf("expected %s, got %s", preset.Params, params)
+	}
+	if params.Preset() != preset {
+		t.Fatalf("expected preset %s, got %v", preset.Name, params.Preset())
+	}
+
+	info.MaxTxPower = 22
+	if err := info.ValidateTxPower(22); err != nil {
+		t.Fatal(err)
+	}
+	if err := info.ValidateTxPower(30); err == nil || err.Error() != "tx power 30 dBm exceeds the device's max of 22 dBm" {
+		t.Fatalf("expected tx power error, got %v", err)
+	}
+}
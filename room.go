@@ -0,0 +1,234 @@
+package meshcore
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/kellegous/poop"
+)
+
+// RoomPost is a message that was posted to a room server and relayed to this
+// device.
+type RoomPost struct {
+	// AuthorPrefix is the first 4 bytes of the public key of the contact that
+	// originally posted the message.
+	AuthorPrefix [4]byte
+	// Author is the contact that posted the message, or nil if the author
+	// is not one of the device's contacts.
+	Author     *Contact
+	SenderTime time.Time
+	Text       string
+}
+
+type RoomOptions struct {
+	// Since is the time of the newest post that has already been seen. Posts
+	// sent before this time are dropped during Sync.
+	Since time.Time
+	// SeenAtSince are the posts sent at Since that have already been seen,
+	// as returned by Room.SeenAtLast. Posts only carry a time to the second,
+	// so other posts sent in that second are still delivered.
+	SeenAtSince []*RoomPost
+	// OnMessage receives messages that were synced from the device but did
+	// not come from the room. If nil, those messages are discarded.
+	OnMessage func(Message)
+}
+
+// Room is a client for a room server. Room servers relay posts from other
+// members as signed-plain messages whose text is prefixed with the original
+// author's public key prefix.
+type Room struct {
+	conn      *Conn
+	key       PublicKey
+	lastSeen  time.Time
+	onMessage func(Message)
+	contacts  []*Contact
+	// seenAtLast holds the posts sent at lastSeen that were delivered.
+	seenAtLast map[roomPostKey]*RoomPost
+}
+
+// roomPostKey identifies a post among those sent in the same second.
+type roomPostKey struct {
+	author [4]byte
+	text   string
+}
+
+func (p *RoomPost) key() roomPostKey {
+	return roomPostKey{author: p.AuthorPrefix, text: p.Text}
+}
+
+// NewRoom creates a client for the room server with the given key.
+func NewRoom(conn *Conn, key PublicKey, opts *RoomOptions) *Room {
+	if opts == nil {
+		opts = &RoomOptions{}
+	}
+	r := &Room{
+		conn:       conn,
+		key:        key,
+		lastSeen:   opts.Since,
+		onMessage:  opts.OnMessage,
+		seenAtLast: make(map[roomPostKey]*RoomPost),
+	}
+	for _, post := range opts.SeenAtSince {
+		if post.SenderTime.Equal(r.lastSeen) {
+			r.seenAtLast[post.key()] = post
+		}
+	}
+	return r
+}
+
+// Key returns the public key of the room server.
+func (r *Room) Key() PublicKey {
+	return r.key
+}
+
+// LastSeen returns the sender time of the newest post that has been synced.
+func (r *Room) LastSeen() time.Time {
+	return r.lastSeen
+}
+
+// SeenAtLast returns the posts sent at LastSeen that have been synced. Save
+// them along with LastSeen and pass them as RoomOptions.SeenAtSince so that
+// they are not delivered again.
+func (r *Room) SeenAtLast() []*RoomPost {
+	posts := make([]*RoomPost, 0, len(r.seenAtLast))
+	for _, post := range r.seenAtLast {
+		posts = append(posts, post)
+	}
+	return posts
+}
+
+// Login logs in to the room server with the given password.
+func (r *Room) Login(ctx context.Context, password string) error {
+	if err := r.conn.Login(ctx, r.key, password); err != nil {
+		return poop.Chain(err)
+	}
+	return nil
+}
+
+// Post sends a new post to the room.
+func (r *Room) Post(ctx context.Context, text string) (*SentNotification, error) {
+	sent, err := r.conn.SendTextMessage(ctx, &r.key, text, TextTypePlain)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	return sent, nil
+}
+
+// Sync pulls all waiting messages from the device and returns the ones that
+// were posted to the room. Authors are resolved against the device's contacts.
+// Messages pulled from the device are gone from it, so if an error stops the
+// sync, the posts pulled before it are returned along with the error.
+func (r *Room) Sync(ctx context.Context) ([]*RoomPost, error) {
+	var posts []*RoomPost
+	var syncErr error
+	for {
+		msg, err := r.conn.SyncNextMessage(ctx)
+		if err != nil {
+			syncErr = poop.Chain(err)
+			break
+		} else if msg == nil {
+			break
+		}
+
+		cm := msg.FromContact()
+		if cm == nil || !bytes.Equal(cm.PubKeyPrefix[:], r.key.Prefix(6)) {
+			if r.onMessage != nil {
+				r.onMessage(msg)
+			}
+			continue
+		}
+
+		post, err := r.parsePost(cm)
+		if err != nil {
+			syncErr = poop.Chain(err)
+			break
+		}
+
+		if r.wasSeen(post) {
+			continue
+		}
+
+		posts = append(posts, post)
+	}
+
+	if err := r.resolveAuthors(ctx, posts); err != nil && syncErr == nil {
+		syncErr = poop.Chain(err)
+	}
+
+	r.markSeen(posts)
+
+	return posts, syncErr
+}
+
+// wasSeen reports whether post is older than the newest post seen or is one
+// of the posts already seen at that time.
+func (r *Room) wasSeen(post *RoomPost) bool {
+	if post.SenderTime.Before(r.lastSeen) {
+		return true
+	}
+	_, ok := r.seenAtLast[post.key()]
+	return ok && post.SenderTime.Equal(r.lastSeen)
+}
+
+func (r *Room) markSeen(posts []*RoomPost) {
+	for _, post := range posts {
+		if post.SenderTime.After(r.lastSeen) {
+			r.lastSeen = post.SenderTime
+			clear(r.seenAtLast)
+		}
+		if post.SenderTime.Equal(r.lastSeen) {
+			r.seenAtLast[post.key()] = post
+		}
+	}
+}
+
+func (r *Room) parsePost(msg *ContactMessage) (*RoomPost, error) {
+	post := &RoomPost{
+		SenderTime: msg.SenderTime,
+		Text:       msg.Text,
+	}
+
+	switch msg.TextType {
+	case TextTypeSignedPlain:
+		if len(msg.Text) < len(post.AuthorPrefix) {
+			return nil, poop.New("signed message is missing author prefix")
+		}
+		copy(post.AuthorPrefix[:], msg.Text)
+		post.Text = msg.Text[len(post.AuthorPrefix):]
+	default:
+		// Messages that aren't signed come from the room server itself.
+		copy(post.AuthorPrefix[:], r.key.Prefix(len(post.AuthorPrefix)))
+	}
+
+	return post, nil
+}
+
+func (r *Room) resolveAuthors(ctx context.Context, posts []*RoomPost) error {
+	refreshed := false
+	for _, post := range posts {
+		post.Author = r.findContact(post.AuthorPrefix[:])
+		if post.Author != nil || refreshed {
+			continue
+		}
+
+		contacts, err := r.conn.GetContacts(ctx, nil)
+		if err != nil {
+			return poop.Chain(err)
+		}
+		r.contacts = contacts
+		refreshed = true
+
+		post.Author = r.findContact(post.AuthorPrefix[:])
+	}
+	return nil
+}
+
+func (r *Room) findContact(prefix []byte) *Contact {
+	for _, contact := range r.contacts {
+		if bytes.HasPrefix(contact.PublicKey.Bytes(), prefix) {
+			return contact
+		}
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package meshcore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func notifyContactMessage(
+	controller *Controller,
+	from PublicKey,
+	textType TextType,
+	sendTime time.Time,
+	text string,
+) {
+	controller.Notify(
+		NotificationTypeContactMsgRecv,
+		BytesFrom(
+			Bytes(from.Prefix(6)...),
+			Byte(0),
+			Byte(byte(textType)),
+			Time(sendTime, binary.LittleEndian),
+			String(text),
+		))
+}
+
+func TestRoomSync(t *testing.T) {
+	roomKey := fakePublicKey(42)
+	author := &Contact{
+		PublicKey:  fakePublicKey(7),
+		Type:       ContactTypeChat,
+		AdvName:    "author",
+		LastAdvert: time.Unix(100, 0),
+		LastMod:    time.Unix(100, 0),
+	}
+
+	t.Run("success", func(t *testing.T) {
+		var others []Message
+		controller := DoCommand(func(conn *Conn) {
+			room := NewRoom(conn, roomKey, &RoomOptions{
+				OnMessage: func(msg Message) {
+					others = append(others, msg)
+				},
+			})
+			posts, err := room.Sync(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(posts) != 2 {
+				t.Fatalf("expected 2 posts, got %d", len(posts))
+			}
+
+			if posts[0].Text != "hi all" || posts[0].Author == nil || posts[0].Author.AdvName != "author" {
+				t.Fatalf("unexpected post: %s", describe(posts[0]))
+			}
+			if !bytes.Equal(posts[0].AuthorPrefix[:], author.PublicKey.Prefix(4)) {
+				t.Fatalf("expected prefix %v, got %v", author.PublicKey.Prefix(4), posts[0].AuthorPrefix)
+			}
+
+			if posts[1].Text != "welcome" || posts[1].Author != nil {
+				t.Fatalf("unexpected post: %s", describe(posts[1]))
+			}
+			if !bytes.Equal(posts[1].AuthorPrefix[:], roomKey.Prefix(4)) {
+				t.Fatalf("expected prefix %v, got %v", roomKey.Prefix(4), posts[1].AuthorPrefix)
+			}
+
+			if len(others) != 1 {
+				t.Fatalf("expected 1 other message, got %d", len(others))
+			}
+
+			if !room.LastSeen().Equal(time.Unix(300, 0)) {
+				t.Fatalf("expected last seen %s, got %s", time.Unix(300, 0), room.LastSeen())
+			}
+		})
+
+		if err := ValidateBytes(controller.Recv(), Command(CommandSyncNextMessage)); err != nil {
+			t.Fatal(err)
+		}
+		notifyContactMessage(
+			controller,
+			roomKey,
+			TextTypeSignedPlain,
+			time.Unix(200, 0),
+			string(author.PublicKey.Prefix(4))+"hi all",
+		)
+
+		if err := ValidateBytes(controller.Recv(), Command(CommandSyncNextMessage)); err != nil {
+			t.Fatal(err)
+		}
+		notifyContactMessage(controller, fakePublicKey(9), TextTypePlain, time.Unix(250, 0), "direct")
+
+		if err := ValidateBytes(controller.Recv(), Command(CommandSyncNextMessage)); err != nil {
+			t.Fatal(err)
+		}
+		notifyContactMessage(controller, roomKey, TextTypePlain, time.Unix(300, 0), "welcome")
+
+		if err := ValidateBytes(controller.Recv(), Command(CommandSyncNextMessage)); err != nil {
+			t.Fatal(err)
+		}
+		controller.Notify(NotificationTypeNoMoreMessages, nil)
+
+		if err := ValidateBytes(controller.Recv(), Command(CommandGetContacts)); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		author.writeTo(&buf)
+		controller.Notify(NotificationTypeContactsStart, nil)
+		controller.Notify(NotificationTypeContact, buf.Bytes())
+		controller.Notify(NotificationTypeEndOfContacts, nil)
+
+		controller.Wait()
+	})
+
+	t.Run("drops old posts", func(t *testing.T) {
+		controller := DoCommand(func(conn *Conn) {
+			room := NewRoom(conn, roomKey, &RoomOptions{
+				Since: time.Unix(500, 0),
+				SeenAtSince: []*RoomPost{
+					{
+						AuthorPrefix: [4]byte(roomKey.Prefix(4)),
+						SenderTime:   time.Unix(500, 0),
+						Text:         "seen",
+					},
+				},
+			})
+			posts, err := room.Sync(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(posts) != 0 {
+				t.Fatalf("expected 0 posts, got %d", len(posts))
+			}
+			if !room.LastSeen().Equal(time.Unix(500, 0)) {
+				t.Fatalf("expected last seen %s, got %s", time.Unix(500, 0), room.LastSeen())
+			}
+		})
+
+		if err := ValidateBytes(controller.Recv(), Command(CommandSyncNextMessage)); err != nil {
+			t.Fatal(err)
+		}
+		notifyContactMessage(controller, roomKey, TextTypePlain, time.Unix(400, 0), "old")
+
+		if err := ValidateBytes(controller.Recv(), Command(CommandSyncNextMessage)); err != nil {
+			t.Fatal(err)
+		}
+		notifyContactMessage(controller, roomKey, TextTypePlain, time.Unix(500, 0), "seen")
+
+		if err := ValidateBytes(controller.Recv(), Command(CommandSyncNextMessage)); err != nil {
+			t.Fatal(err)
+		}
+		controller.Notify(NotificationTypeNoMoreMessages, nil)
+
+		controller.Wait()
+	})
+
+	t.Run("posts in the same second", func(t *testing.T) {
+		syncPosts := func(controller *Controller, texts ...string) {
+			for _, text := range texts {
+				if err := ValidateBytes(controller.Recv(), Command(CommandSyncNextMessage)); err != nil {
+					t.Fatal(err)
+				}
+				notifyContactMessage(controller, roomKey, TextTypePlain, time.Unix(600, 0), text)
+			}
+
+			if err := ValidateBytes(controller.Recv(), Command(CommandSyncNextMessage)); err != nil {
+				t.Fatal(err)
+			}
+			controller.Notify(NotificationTypeNoMoreMessages, nil)
+
+			if err := ValidateBytes(controller.Recv(), Command(CommandGetContacts)); err != nil {
+				t.Fatal(err)
+			}
+			controller.Notify(NotificationTypeContactsStart, nil)
+			controller.Notify(NotificationTypeEndOfContacts, nil)
+		}
+
+		var first, second []*RoomPost
+		controller := DoCommand(func(conn *Conn) {
+			room := NewRoom(conn, roomKey, nil)
+
+			var err error
+			first, err = room.Sync(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err = room.Sync(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(room.SeenAtLast()) != 3 {
+				t.Fatalf("expected 3 posts seen at last, got %d", len(room.SeenAtLast()))
+			}
+		})
+
+		syncPosts(controller, "one", "two")
+		syncPosts(controller, "two", "three")
+		controller.Wait()
+
+		if len(first) != 2 || first[0].Text != "one" || first[1].Text != "two" {
+			t.Fatalf("unexpected first sync: %s", describe(first))
+		}
+		if len(second) != 1 || second[0].Text != "three" {
+			t.Fatalf("unexpected second sync: %s", describe(second))
+		}
+	})
+
+	t.Run("returns posts synced before an error", func(t *testing.T) {
+		controller := DoCommand(func(conn *Conn) {
+			room := NewRoom(conn, roomKey, nil)
+			posts, err := room.Sync(t.Context())
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if len(posts) != 1 || posts[0].Text != "welcome" {
+				t.Fatalf("unexpected posts: %s", describe(posts))
+			}
+			if !room.LastSeen().Equal(time.Unix(300, 0)) {
+				t.Fatalf("expected last seen %s, got %s", time.Unix(300, 0), room.LastSeen())
+			}
+		})
+
+		if err := ValidateBytes(controller.Recv(), Command(CommandSyncNextMessage)); err != nil {
+			t.Fatal(err)
+		}
+		notifyContactMessage(controller, roomKey, TextTypePlain, time.Unix(300, 0), "welcome")
+
+		if err := ValidateBytes(controller.Recv(), Command(CommandSyncNextMessage)); err != nil {
+			t.Fatal(err)
+		}
+		controller.Notify(NotificationTypeErr, BytesFrom(Byte(byte(ErrorCodeFileIOError))))
+
+		if err := ValidateBytes(controller.Recv(), Command(CommandGetContacts)); err != nil {
+			t.Fatal(err)
+		}
+		controller.Notify(NotificationTypeContactsStart, nil)
+		controller.Notify(NotificationTypeEndOfContacts, nil)
+
+		controller.Wait()
+	})
+
+	t.Run("missing author prefix", func(t *testing.T) {
+		controller := DoCommand(func(conn *Conn) {
+			room := NewRoom(conn, roomKey, nil)
+			if _, err := room.Sync(t.Context()); err == nil || err.Error() != "signed message is missing author prefix" {
+				t.Fatalf("expected error: signed message is missing author prefix, got %v", err)
+			}
+		})
+
+		if err := ValidateBytes(controller.Recv(), Command(CommandSyncNextMessage)); err != nil {
+			t.Fatal(err)
+		}
+		notifyContactMessage(controller, roomKey, TextTypeSignedPlain, time.Unix(200, 0), "ab")
+
+		controller.Wait()
+	})
+}
+
+func TestRoomPost(t *testing.T) {
+	roomKey := fakePublicKey(42)
+
+	controller := DoCommand(func(conn *Conn) {
+		room := NewRoom(conn, roomKey, nil)
+		sent, err := room.Post(t.Context(), "hello room")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sent.ExpectedAckCRC != 0x1234 {
+			t.Fatalf("expected ack crc 0x1234, got %x", sent.ExpectedAckCRC)
+		}
+	})
+
+	if err := ValidateBytes(
+		controller.Recv(),
+		Command(CommandSendTxtMsg),
+		Byte(byte(TextTypePlain)),
+		Byte(0),
+		AnyBytes(4),
+		Bytes(roomKey.Prefix(6)...),
+		String("hello room"),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	controller.Notify(NotificationTypeSent, BytesFrom(
+		Byte(0),
+		Uint32(0x1234, binary.LittleEndian),
+		Uint32(1000, binary.LittleEndian),
+	))
+
+	controller.Wait()
+}
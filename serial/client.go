@@ -2,19 +2,14 @@ package serial
 
 import (
 	"context"
-	"encoding/binary"
 	"io"
+	"log/slog"
 
 	"github.com/kellegous/meshcore"
 	"github.com/kellegous/poop"
 	"go.bug.st/serial"
 )
 
-const (
-	incomingFrameType = 0x3e // ">"
-	outgoingFrameType = 0x3c // "<"
-)
-
 func Connect(
 	ctx context.Context,
 	address string,
@@ -25,12 +20,64 @@ func Connect(
 		opt(options)
 	}
 
-	port, err := serial.Open(address, &serial.Mode{
-		BaudRate: 115200,
-		DataBits: 8,
-		StopBits: serial.OneStopBit,
-		Parity:   serial.NoParity,
-	})
+	// The lock is taken before opening, since the open marks the port
+	// exclusive to this process, which would stop the lock from opening it.
+	var lock io.Closer
+	if options.exclusive {
+		l, err := lockPort(address)
+		if err != nil {
+			return nil, poop.Chain(err)
+		}
+		lock = l
+	}
+
+	port, err := serial.Open(address, options.mode())
+	if err != nil {
+		closeLock(lock)
+		return nil, poop.Chain(err)
+	}
+
+	logger := options.logger().With(slog.String("address", address))
+	logger.Info("serial port opened")
+
+	conn, err := newConnection(port, lock, logger, options)
+	if err != nil {
+		port.Close()
+		closeLock(lock)
+		return nil, poop.Chain(err)
+	}
+	return conn, nil
+}
+
+func closeLock(lock io.Closer) {
+	if lock != nil {
+		lock.Close()
+	}
+}
+
+// NewConnection starts a connection over port, which carries frames in the
+// serial framing. The framing is also used over TCP by devices with WiFi, so
+// port may be a net.Conn. The name identifies the port in logs.
+// The mode options, like BaudRate, and Exclusive do not apply.
+func NewConnection(
+	port io.ReadWriteCloser,
+	name string,
+	opts ...ConnectOption,
+) (*meshcore.Conn, error) {
+	options := &ConnectOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return newConnection(port, nil, options.logger().With(slog.String("address", name)), options)
+}
+
+func newConnection(
+	port io.ReadWriteCloser,
+	lock io.Closer,
+	logger *slog.Logger,
+	options *ConnectOptions,
+) (*meshcore.Conn, error) {
+	r, err := newTimeoutReader(port, options.readTimeout)
 	if err != nil {
 		return nil, poop.Chain(err)
 	}
@@ -39,73 +86,54 @@ func Connect(
 
 	transport := &tx{
 		port:               port,
+		lock:               lock,
 		NotificationCenter: notificationCenter,
-		opts:               options,
+		logger:             logger,
 	}
 
-	// TODO(kellegous): What to do with these errors?
+	// Reads recover from anything the device sends, so an error means the
+	// port itself failed. Waiting commands are shut down and later writes
+	// return the error.
 	onRecvError := func(err error) {
 		// suppress errors if the transport is disconnected
 		if transport.isDisconnected.Load() {
 			return
 		}
-		panic(err)
+		logger.Error("serial read failed", slog.Any("error", err))
+		transport.fail(err)
+	}
+
+	frames := newFrameReader(r)
+	frames.onDiscard = func(output []byte) {
+		logger.Debug("serial output discarded", slog.Int("size", len(output)))
+	}
+	frames.onReboot = func(e *RebootEvent) {
+		logger.Warn("device reboot detected", slog.String("reason", e.Reason.String()))
+		if fn := options.onReboot; fn != nil {
+			fn(e)
+		}
 	}
 
 	go func() {
 		defer port.Close()
 
 		for {
-			// the js library does a lot of nonsense that seems
-			// unsound. For instance, if the type is wrong, it
-			// reads the next byte. That seems destined to fail.
-			var hdr header
-			if err := hdr.readFrom(port); err != nil {
-				onRecvError(err)
-				return
-			}
-
-			if hdr.Length == 0 {
-				onRecvError(poop.New("frame length is 0"))
-				return
-			}
-
-			data := make([]byte, hdr.Length)
-			if _, err := io.ReadFull(port, data); err != nil {
+			data, err := frames.next()
+			if err != nil {
 				onRecvError(err)
 				return
 			}
 
 			code := meshcore.NotificationCode(data[0])
-			if nf := options.onRecv; nf != nil {
-				nf(code, data[1:])
-			}
 
 			notificationCenter.Publish(code, data[1:])
 		}
 	}()
 
-	return meshcore.NewConnection(transport), nil
-}
-
-type header struct {
-	Type   byte
-	Length uint16
-}
-
-func (h *header) readFrom(r io.Reader) error {
-	if err := binary.Read(r, binary.LittleEndian, &h.Type); err != nil {
-		return poop.Chain(err)
-	}
-
-	// TODO(kellegous): why would we receive an outgoing frame?
-	if h.Type != incomingFrameType && h.Type != outgoingFrameType {
-		return poop.Newf("invalid frame type: %d", h.Type)
+	conn := meshcore.NewConnection(transport)
+	if options.log != nil {
+		conn.SetLogging(options.log)
 	}
-
-	if err := binary.Read(r, binary.LittleEndian, &h.Length); err != nil {
-		return poop.Chain(err)
-	}
-
-	return nil
+	conn.Use(options.middleware...)
+	return conn, nil
 }
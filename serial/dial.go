@@ -0,0 +1,113 @@
+package serial
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/kellegous/meshcore"
+	"github.com/kellegous/poop"
+)
+
+func init() {
+	meshcore.RegisterDialer("serial", dial)
+}
+
+// dial connects to a URL like serial:///dev/ttyUSB0?baud=115200. The query
+// may also set parity (none, odd, even, mark or space), stopbits (1, 1.5 or
+// 2), dtr and rts (true or false), read_timeout (a duration like 500ms) and
+// exclusive (true or false).
+func dial(ctx context.Context, addr string, query url.Values) (*meshcore.Conn, error) {
+	opts, err := dialOptions(query)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	return Connect(ctx, addr, opts...)
+}
+
+func dialOptions(query url.Values) ([]ConnectOption, error) {
+	if err := meshcore.CheckDialQuery(
+		query,
+		"baud",
+		"parity",
+		"stopbits",
+		"dtr",
+		"rts",
+		"read_timeout",
+		"exclusive",
+	); err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	var opts []ConnectOption
+	if v := query.Get("baud"); v != "" {
+		baud, err := strconv.Atoi(v)
+		if err != nil || baud <= 0 {
+			return nil, poop.Newf("invalid baud rate: %q", v)
+		}
+		opts = append(opts, BaudRate(baud))
+	}
+
+	if v := query.Get("parity"); v != "" {
+		parity, ok := map[string]Parity{
+			"none":  ParityNone,
+			"odd":   ParityOdd,
+			"even":  ParityEven,
+			"mark":  ParityMark,
+			"space": ParitySpace,
+		}[v]
+		if !ok {
+			return nil, poop.Newf("invalid parity: %q", v)
+		}
+		opts = append(opts, WithParity(parity))
+	}
+
+	if v := query.Get("stopbits"); v != "" {
+		stopBits, ok := map[string]StopBits{
+			"1":   StopBitsOne,
+			"1.5": StopBitsOnePointFive,
+			"2":   StopBitsTwo,
+		}[v]
+		if !ok {
+			return nil, poop.Newf("invalid stop bits: %q", v)
+		}
+		opts = append(opts, WithStopBits(stopBits))
+	}
+
+	for _, line := range []struct {
+		key string
+		opt func(bool) ConnectOption
+	}{
+		{"dtr", DTR},
+		{"rts", RTS},
+	} {
+		if v := query.Get(line.key); v != "" {
+			on, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, poop.Newf("invalid %s: %q", line.key, v)
+			}
+			opts = append(opts, line.opt(on))
+		}
+	}
+
+	if v := query.Get("read_timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, poop.Newf("invalid read timeout: %q", v)
+		}
+		opts = append(opts, ReadTimeout(d))
+	}
+
+	if v := query.Get("exclusive"); v != "" {
+		exclusive, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, poop.Newf("invalid exclusive: %q", v)
+		}
+		if exclusive {
+			opts = append(opts, Exclusive())
+		}
+	}
+
+	return opts, nil
+}
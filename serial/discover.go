@@ -0,0 +1,139 @@
+package serial
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/kellegous/meshcore"
+	"github.com/kellegous/poop"
+)
+
+// ProbeTimeout is how long Probe waits for a port to answer.
+const ProbeTimeout = 2 * time.Second
+
+// probeAppTargetVer is the protocol version sent in the probe's DeviceQuery.
+const probeAppTargetVer = 3
+
+// These find and open ports. Tests replace them.
+var (
+	systemPorts = listPorts
+	openPort    = Connect
+)
+
+// Port is a serial port on the system.
+type Port struct {
+	// Name is the address of the port to pass to Connect, such as
+	// /dev/ttyUSB0 or COM3.
+	Name  string
+	IsUSB bool
+	// VID and PID are the USB vendor and product IDs, in hex.
+	VID          string
+	PID          string
+	SerialNumber string
+	// Product describes the port. It is OS-dependent and may be empty.
+	Product string
+}
+
+// ListPorts returns the serial ports on the system.
+func ListPorts() ([]*Port, error) {
+	ports, err := systemPorts()
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	return ports, nil
+}
+
+// Device is a MeshCore companion found on a serial port.
+type Device struct {
+	Port       *Port
+	DeviceInfo *meshcore.DeviceInfo
+	SelfInfo   *meshcore.SelfInfo
+}
+
+// ProbeError is returned for a port that could not be opened, such as when
+// permission is denied or another process is using it.
+type ProbeError struct {
+	Port *Port
+	Err  error
+}
+
+func (e *ProbeError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Port.Name, e.Err)
+}
+
+func (e *ProbeError) Unwrap() error {
+	return e.Err
+}
+
+// Probe opens the port and checks that it answers like a MeshCore companion,
+// waiting at most ProbeTimeout. If the port cannot be opened, the error is a
+// *ProbeError.
+func Probe(ctx context.Context, port *Port, opts ...ConnectOption) (*Device, error) {
+	ctx, cancel := context.WithTimeout(ctx, ProbeTimeout)
+	defer cancel()
+
+	conn, err := openPort(ctx, port.Name, opts...)
+	if err != nil {
+		return nil, &ProbeError{Port: port, Err: err}
+	}
+	defer conn.Disconnect()
+
+	deviceInfo, err := conn.DeviceQuery(ctx, probeAppTargetVer)
+	if err != nil {
+		return nil, poop.ChainWithf(err, "%s did not answer DeviceQuery", port.Name)
+	}
+
+	selfInfo, err := conn.GetSelfInfo(ctx)
+	if err != nil {
+		return nil, poop.ChainWithf(err, "%s did not answer AppStart", port.Name)
+	}
+
+	return &Device{
+		Port:       port,
+		DeviceInfo: deviceInfo,
+		SelfInfo:   selfInfo,
+	}, nil
+}
+
+// DiscoverDevices probes each USB serial port in turn and yields the ones
+// that are MeshCore companions. Ports that open but do not answer are
+// skipped. A port that cannot be opened yields a *ProbeError, after which
+// discovery continues with the next port unless the loop stops.
+func DiscoverDevices(ctx context.Context, opts ...ConnectOption) iter.Seq2[*Device, error] {
+	return func(yield func(*Device, error) bool) {
+		ports, err := ListPorts()
+		if err != nil {
+			yield(nil, poop.Chain(err))
+			return
+		}
+
+		for _, port := range ports {
+			if !port.IsUSB {
+				continue
+			}
+
+			if err := ctx.Err(); err != nil {
+				yield(nil, poop.Chain(err))
+				return
+			}
+
+			device, err := Probe(ctx, port, opts...)
+			var probeErr *ProbeError
+			if errors.As(err, &probeErr) {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			} else if err != nil {
+				continue
+			}
+
+			if !yield(device, nil) {
+				return
+			}
+		}
+	}
+}
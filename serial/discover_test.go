@@ -0,0 +1,173 @@
+package serial
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/kellegous/meshcore"
+)
+
+var errPortBusy = errors.New("port busy")
+
+// fakeDevice answers DeviceQuery and AppStart over one end of a pipe like a
+// MeshCore companion. If silent, it prints some output and hangs up instead,
+// like a port with some other device on it.
+type fakeDevice struct {
+	model  string
+	name   string
+	silent bool
+}
+
+func (d *fakeDevice) serve(conn net.Conn) {
+	defer conn.Close()
+
+	if d.silent {
+		conn.Write([]byte("ready\r\n"))
+		return
+	}
+
+	for {
+		var hdr [3]byte
+		if _, err := io.ReadFull(conn, hdr[:]); err != nil {
+			return
+		}
+		cmd := make([]byte, binary.LittleEndian.Uint16(hdr[1:]))
+		if _, err := io.ReadFull(conn, cmd); err != nil {
+			return
+		}
+
+		switch meshcore.CommandCode(cmd[0]) {
+		case meshcore.CommandDeviceQuery:
+			conn.Write(frame(d.deviceInfo()...))
+		case meshcore.CommandAppStart:
+			conn.Write(frame(d.selfInfo()...))
+		}
+	}
+}
+
+func (d *fakeDevice) deviceInfo() []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(byte(meshcore.NotificationTypeDeviceInfo))
+	buf.WriteByte(3)
+	buf.Write(make([]byte, 6))
+	buf.Write(append([]byte("19 Jan 2026"), 0))
+	buf.WriteString(d.model)
+	return buf.Bytes()
+}
+
+func (d *fakeDevice) selfInfo() []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(byte(meshcore.NotificationTypeSelfInfo))
+	buf.Write([]byte{1, 22, 22})
+	buf.Write(bytes.Repeat([]byte{0x42}, 32))
+	buf.Write(make([]byte, 8+3+1))
+	binary.Write(&buf, binary.LittleEndian, uint32(869525))
+	binary.Write(&buf, binary.LittleEndian, uint32(250000))
+	buf.Write([]byte{11, 5})
+	buf.WriteString(d.name)
+	return buf.Bytes()
+}
+
+// fakePorts replaces the system's ports with ports whose devices are looked
+// up by name. Ports without a device fail to open.
+func fakePorts(t *testing.T, ports []*Port, devices map[string]*fakeDevice) *[]string {
+	var opened []string
+
+	prevSystemPorts, prevOpenPort := systemPorts, openPort
+	t.Cleanup(func() {
+		systemPorts, openPort = prevSystemPorts, prevOpenPort
+	})
+
+	systemPorts = func() ([]*Port, error) {
+		return ports, nil
+	}
+	openPort = func(ctx context.Context, name string, opts ...ConnectOption) (*meshcore.Conn, error) {
+		opened = append(opened, name)
+		device, ok := devices[name]
+		if !ok {
+			return nil, errPortBusy
+		}
+		host, dev := net.Pipe()
+		go device.serve(dev)
+		return NewConnection(host, name, opts...)
+	}
+
+	return &opened
+}
+
+func TestProbe(t *testing.T) {
+	meshcorePort := &Port{Name: "/dev/ttyUSB0", IsUSB: true}
+	otherPort := &Port{Name: "/dev/ttyUSB1", IsUSB: true}
+	busyPort := &Port{Name: "/dev/ttyUSB2", IsUSB: true}
+	fakePorts(t, nil, map[string]*fakeDevice{
+		meshcorePort.Name: {model: "Heltec V3", name: "node"},
+		otherPort.Name:    {silent: true},
+	})
+
+	device, err := Probe(t.Context(), meshcorePort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.Port != meshcorePort || device.DeviceInfo.ManufacturerModel != "Heltec V3" || device.SelfInfo.Name != "node" {
+		t.Fatalf("unexpected device: %+v", device)
+	}
+
+	_, err = Probe(t.Context(), otherPort)
+	var probeErr *ProbeError
+	if err == nil || errors.As(err, &probeErr) {
+		t.Fatalf("expected an error that is not a *ProbeError, got %v", err)
+	}
+
+	_, err = Probe(t.Context(), busyPort)
+	if !errors.As(err, &probeErr) || probeErr.Port != busyPort || !errors.Is(err, errPortBusy) {
+		t.Fatalf("expected a *ProbeError for %s, got %v", busyPort.Name, err)
+	}
+	if expected := "/dev/ttyUSB2: port busy"; err.Error() != expected {
+		t.Fatalf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestDiscoverDevices(t *testing.T) {
+	opened := fakePorts(t,
+		[]*Port{
+			{Name: "/dev/ttyS0"},
+			{Name: "/dev/ttyUSB0", IsUSB: true},
+			{Name: "/dev/ttyUSB1", IsUSB: true},
+			{Name: "/dev/ttyUSB2", IsUSB: true},
+			{Name: "/dev/ttyUSB3", IsUSB: true},
+		},
+		map[string]*fakeDevice{
+			"/dev/ttyS0":   {model: "Heltec V3", name: "serial"},
+			"/dev/ttyUSB0": {model: "Heltec V3", name: "a"},
+			"/dev/ttyUSB1": {silent: true},
+			"/dev/ttyUSB3": {model: "RAK 4631", name: "b"},
+		})
+
+	var names, failed []string
+	for device, err := range DiscoverDevices(t.Context()) {
+		var probeErr *ProbeError
+		if errors.As(err, &probeErr) {
+			failed = append(failed, probeErr.Port.Name)
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, device.SelfInfo.Name)
+	}
+
+	if expected := []string{"a", "b"}; !slices.Equal(names, expected) {
+		t.Fatalf("expected devices %v, got %v", expected, names)
+	}
+	if expected := []string{"/dev/ttyUSB2"}; !slices.Equal(failed, expected) {
+		t.Fatalf("expected failed ports %v, got %v", expected, failed)
+	}
+	if slices.Contains(*opened, "/dev/ttyS0") {
+		t.Fatal("expected ports that are not USB to be skipped")
+	}
+}
@@ -0,0 +1,68 @@
+package serial_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/kellegous/meshcore"
+	meshcore_serial "github.com/kellegous/meshcore/serial"
+)
+
+// Find the MeshCore companions attached over USB.
+func ExampleDiscoverDevices() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for device, err := range meshcore_serial.DiscoverDevices(ctx) {
+		var probeErr *meshcore_serial.ProbeError
+		if errors.As(err, &probeErr) {
+			// The port could not be opened, perhaps because another program
+			// has it.
+			log.Print(err)
+			continue
+		} else if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("%s (%s:%s): %s %s\n",
+			device.Port.Name,
+			device.Port.VID,
+			device.Port.PID,
+			device.DeviceInfo.ManufacturerModel,
+			&device.SelfInfo.PublicKey)
+	}
+}
+
+// Keep the device's clock in sync, checking it again whenever the device
+// reboots.
+func ExampleOnReboot() {
+	ctx := context.Background()
+
+	// The TimeSync needs the connection, which does not exist yet when the
+	// option is created.
+	var timeSync atomic.Pointer[meshcore.TimeSync]
+
+	conn, err := meshcore_serial.Connect(
+		ctx,
+		"/dev/ttyUSB0",
+		meshcore_serial.OnReboot(func(*meshcore_serial.RebootEvent) {
+			if s := timeSync.Load(); s != nil {
+				s.Trigger()
+			}
+		}),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Disconnect()
+
+	s := meshcore.NewTimeSync(conn, nil)
+	timeSync.Store(s)
+	if err := s.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,229 @@
+package serial
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/kellegous/poop"
+)
+
+const (
+	incomingFrameType = 0x3e // ">"
+	outgoingFrameType = 0x3c // "<"
+
+	// maxFrameLength is larger than any frame the companion firmware sends,
+	// so a longer length means the stream is out of sync.
+	maxFrameLength = 512
+
+	// maxRebootOutput is how much discarded output a RebootEvent keeps.
+	maxRebootOutput = 256
+)
+
+// RebootReason is why a RebootEvent was raised.
+type RebootReason int
+
+const (
+	// RebootStartupOutput means the device printed its boot banner.
+	RebootStartupOutput RebootReason = iota
+	// RebootFramingReset means a frame was cut short or had an invalid
+	// length, so the stream was resynchronized.
+	RebootFramingReset
+)
+
+func (r RebootReason) String() string {
+	if r == RebootFramingReset {
+		return "framing reset"
+	}
+	return "startup output"
+}
+
+// RebootEvent reports that the device appears to have rebooted while
+// connected. Anything it has been told since connecting, such as the app
+// start and the time, should be assumed lost.
+type RebootEvent struct {
+	Reason RebootReason
+	At     time.Time
+	// Output is the tail of the bytes that were discarded while looking for
+	// the next frame.
+	Output []byte
+}
+
+// bootBanners start the lines printed by device bootloaders when they start.
+// Boards with native USB, like the nRF52, drop off the bus instead, which
+// fails the port.
+var bootBanners = [][]byte{
+	[]byte("rst:0x"),   // ESP32
+	[]byte("ets "),     // ESP32, ESP8266
+	[]byte("ESP-ROM:"), // ESP32-S3, ESP32-C3
+}
+
+var errReadTimeout = errors.New("read timed out")
+
+// timeoutReader turns reads that time out into errReadTimeout.
+type timeoutReader struct {
+	r       io.Reader
+	timeout time.Duration
+}
+
+func newTimeoutReader(r io.Reader, timeout time.Duration) (*timeoutReader, error) {
+	if timeout > 0 {
+		if p, ok := r.(interface{ SetReadTimeout(time.Duration) error }); ok {
+			if err := p.SetReadTimeout(timeout); err != nil {
+				return nil, poop.Chain(err)
+			}
+		}
+	}
+	return &timeoutReader{r: r, timeout: timeout}, nil
+}
+
+func (r *timeoutReader) Read(p []byte) (int, error) {
+	// Connections like net.Conn take a deadline for each read rather than a
+	// timeout.
+	if c, ok := r.r.(interface{ SetReadDeadline(time.Time) error }); ok && r.timeout > 0 {
+		if err := c.SetReadDeadline(time.Now().Add(r.timeout)); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.r.Read(p)
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return n, errReadTimeout
+	case n == 0 && err == nil:
+		// serial ports return nothing when the timeout passes.
+		return 0, errReadTimeout
+	}
+	return n, err
+}
+
+// frameReader reads frames from a port. Anything between frames is skipped
+// until the next frame marker, and a frame that is cut short is dropped, so
+// the reader recovers when the device reboots under it.
+type frameReader struct {
+	r        *bufio.Reader
+	now      func() time.Time
+	onReboot func(*RebootEvent)
+	// onDiscard is called with output that was skipped without a reboot
+	// being detected, such as debug logging.
+	onDiscard func([]byte)
+
+	discarded []byte
+	rebooted  bool
+}
+
+func newFrameReader(r io.Reader) *frameReader {
+	return &frameReader{
+		r:   bufio.NewReader(r),
+		now: time.Now,
+	}
+}
+
+// next returns the payload of the next frame.
+func (f *frameReader) next() ([]byte, error) {
+	for {
+		b, err := f.r.ReadByte()
+		if errors.Is(err, errReadTimeout) {
+			f.flush()
+			continue
+		} else if err != nil {
+			return nil, poop.Chain(err)
+		}
+
+		// TODO(kellegous): why would we receive an outgoing frame?
+		if b != incomingFrameType && b != outgoingFrameType {
+			f.discard(b)
+			continue
+		}
+
+		data, err := f.readFrame()
+		if errors.Is(err, errReadTimeout) || errors.Is(err, errInvalidLength) {
+			// A bad frame straight after a good one means the device stopped
+			// part way through. Otherwise the marker was just part of other
+			// output.
+			if len(f.discarded) == 0 {
+				f.reboot(RebootFramingReset)
+			}
+			f.discard(b)
+			continue
+		} else if err != nil {
+			return nil, poop.Chain(err)
+		}
+
+		f.flush()
+		return data, nil
+	}
+}
+
+var errInvalidLength = errors.New("invalid frame length")
+
+func (f *frameReader) readFrame() ([]byte, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(f.r, hdr[:]); err != nil {
+		return nil, err
+	}
+
+	n := binary.LittleEndian.Uint16(hdr[:])
+	if n == 0 || n > maxFrameLength {
+		return nil, errInvalidLength
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(f.r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// discard skips a byte that is not part of a frame, checking each line of
+// skipped output for a boot banner.
+func (f *frameReader) discard(b byte) {
+	f.discarded = append(f.discarded, b)
+	if len(f.discarded) > maxRebootOutput {
+		f.discarded = f.discarded[len(f.discarded)-maxRebootOutput:]
+	}
+
+	if b != '\n' || f.rebooted {
+		return
+	}
+	line := f.discarded[:len(f.discarded)-1]
+	if i := bytes.LastIndexByte(line, '\n'); i >= 0 {
+		line = line[i+1:]
+	}
+	for _, banner := range bootBanners {
+		if bytes.HasPrefix(line, banner) {
+			f.reboot(RebootStartupOutput)
+			return
+		}
+	}
+}
+
+// reboot reports a reboot, unless one has already been reported for the
+// output being discarded.
+func (f *frameReader) reboot(reason RebootReason) {
+	if f.rebooted {
+		return
+	}
+	f.rebooted = true
+
+	if f.onReboot != nil {
+		f.onReboot(&RebootEvent{
+			Reason: reason,
+			At:     f.now(),
+			Output: bytes.Clone(f.discarded),
+		})
+	}
+}
+
+// flush ends a run of discarded output.
+func (f *frameReader) flush() {
+	if len(f.discarded) > 0 && !f.rebooted && f.onDiscard != nil {
+		f.onDiscard(f.discarded)
+	}
+	f.discarded = nil
+	f.rebooted = false
+}
@@ -0,0 +1,165 @@
+package serial
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// chunkReader returns each chunk in a read of its own, and nothing, as a
+// serial port does when its read timeout passes, for each nil chunk.
+type chunkReader struct {
+	chunks [][]byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	c := r.chunks[0]
+	r.chunks = r.chunks[1:]
+	return copy(p, c), nil
+}
+
+func frame(data ...byte) []byte {
+	return append([]byte{incomingFrameType, byte(len(data)), byte(len(data) >> 8)}, data...)
+}
+
+func TestFrameReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		chunks  [][]byte
+		frames  [][]byte
+		reboots []RebootReason
+	}{
+		{
+			name:   "frames",
+			chunks: [][]byte{frame(1, 2), frame(3)},
+			frames: [][]byte{{1, 2}, {3}},
+		},
+		{
+			name: "startup output",
+			chunks: [][]byte{
+				frame(1),
+				[]byte("ets Jul 29 2019 12:21:46\r\n\r\nrst:0x1 (POWERON_RESET),boot:0x13\r\n"),
+				frame(2),
+			},
+			frames:  [][]byte{{1}, {2}},
+			reboots: []RebootReason{RebootStartupOutput},
+		},
+		{
+			name:   "debug output",
+			chunks: [][]byte{[]byte("sent 3 packets -> 0x1234\n"), frame(1)},
+			frames: [][]byte{{1}},
+		},
+		{
+			name:    "frame cut short",
+			chunks:  [][]byte{frame(1), frame(1, 2, 3)[:3], nil, frame(2)},
+			frames:  [][]byte{{1}, {2}},
+			reboots: []RebootReason{RebootFramingReset},
+		},
+		{
+			name:    "invalid length",
+			chunks:  [][]byte{{incomingFrameType, 0xff, 0xff}, frame(1)},
+			frames:  [][]byte{{1}},
+			reboots: []RebootReason{RebootFramingReset},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := newTimeoutReader(&chunkReader{chunks: test.chunks}, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var reboots []RebootReason
+			f := newFrameReader(r)
+			f.onReboot = func(e *RebootEvent) {
+				reboots = append(reboots, e.Reason)
+			}
+
+			var frames [][]byte
+			for {
+				data, err := f.next()
+				if errors.Is(err, io.EOF) {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				frames = append(frames, data)
+			}
+
+			if !reflect.DeepEqual(frames, test.frames) {
+				t.Fatalf("expected frames %v, got %v", test.frames, frames)
+			}
+			if !reflect.DeepEqual(reboots, test.reboots) {
+				t.Fatalf("expected reboots %v, got %v", test.reboots, reboots)
+			}
+		})
+	}
+}
+
+func TestDialOptions(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected ConnectOptions
+		err      string
+	}{
+		{
+			query:    "baud=9600&parity=even&stopbits=2&read_timeout=250ms&exclusive=true",
+			expected: ConnectOptions{baud: 9600, parity: ParityEven, stopBits: StopBitsTwo, readTimeout: 250 * time.Millisecond, exclusive: true},
+		},
+		{query: "baud=fast", err: `invalid baud rate: "fast"`},
+		{query: "parity=sometimes", err: `invalid parity: "sometimes"`},
+		{query: "dtr=maybe", err: `invalid dtr: "maybe"`},
+		{query: "flow=rtscts", err: `unknown option: "flow"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			query, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opts, err := dialOptions(query)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			var options ConnectOptions
+			for _, opt := range opts {
+				opt(&options)
+			}
+			if !reflect.DeepEqual(options, test.expected) {
+				t.Fatalf("expected %+v, got %+v", test.expected, options)
+			}
+		})
+	}
+}
+
+func TestDialOptionsLines(t *testing.T) {
+	query, _ := url.ParseQuery("dtr=false")
+	opts, err := dialOptions(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var options ConnectOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	bits := options.mode().InitialStatusBits
+	if bits == nil || bits.DTR || !bits.RTS {
+		t.Fatalf("expected DTR off and RTS on, got %+v", bits)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package serial
+
+import (
+	"io"
+	"runtime"
+
+	"github.com/kellegous/poop"
+)
+
+// lockPort returns no lock on windows, where ports are opened for exclusive
+// access regardless.
+func lockPort(address string) (io.Closer, error) {
+	if runtime.GOOS == "windows" {
+		return nil, nil
+	}
+	return nil, poop.Newf("locking serial ports is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,29 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package serial
+
+import (
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/kellegous/poop"
+)
+
+// lockPort takes an advisory lock on the port's device, as other serial
+// tools do, which is held until the returned Closer is closed.
+func lockPort(address string) (io.Closer, error) {
+	f, err := os.OpenFile(address, os.O_RDONLY|syscall.O_NONBLOCK|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, poop.Newf("%s is locked by another process", address)
+		}
+		return nil, poop.Chain(err)
+	}
+	return f, nil
+}
@@ -1,22 +1,189 @@
 package serial
 
-import "github.com/kellegous/meshcore"
+import (
+	"log/slog"
+	"time"
+
+	"github.com/kellegous/meshcore"
+	"go.bug.st/serial"
+)
+
+// Parity is the parity checking of a serial port.
+type Parity int
+
+const (
+	ParityNone Parity = iota
+	ParityOdd
+	ParityEven
+	ParityMark
+	ParitySpace
+)
+
+// StopBits is the number of stop bits of a serial port.
+type StopBits int
+
+const (
+	StopBitsOne StopBits = iota
+	StopBitsOnePointFive
+	StopBitsTwo
+)
 
 type ConnectOptions struct {
-	onRecv func(code meshcore.NotificationCode, data []byte)
-	onSend func(code meshcore.CommandCode, data []byte)
+	baud        int
+	parity      Parity
+	stopBits    StopBits
+	dtr         *bool
+	rts         *bool
+	readTimeout time.Duration
+	exclusive   bool
+	onReboot    func(*RebootEvent)
+	middleware  []meshcore.Middleware
+	log         *meshcore.LogOptions
+}
+
+const defaultBaudRate = 115200
+
+func (o *ConnectOptions) baudRate() int {
+	if o.baud > 0 {
+		return o.baud
+	}
+	return defaultBaudRate
+}
+
+func (o *ConnectOptions) mode() *serial.Mode {
+	mode := &serial.Mode{
+		BaudRate: o.baudRate(),
+		DataBits: 8,
+	}
+
+	switch o.parity {
+	case ParityOdd:
+		mode.Parity = serial.OddParity
+	case ParityEven:
+		mode.Parity = serial.EvenParity
+	case ParityMark:
+		mode.Parity = serial.MarkParity
+	case ParitySpace:
+		mode.Parity = serial.SpaceParity
+	default:
+		mode.Parity = serial.NoParity
+	}
+
+	switch o.stopBits {
+	case StopBitsOnePointFive:
+		mode.StopBits = serial.OnePointFiveStopBits
+	case StopBitsTwo:
+		mode.StopBits = serial.TwoStopBits
+	default:
+		mode.StopBits = serial.OneStopBit
+	}
+
+	// Both lines are asserted at open unless told otherwise.
+	if o.dtr != nil || o.rts != nil {
+		mode.InitialStatusBits = &serial.ModemOutputBits{DTR: true, RTS: true}
+		if o.dtr != nil {
+			mode.InitialStatusBits.DTR = *o.dtr
+		}
+		if o.rts != nil {
+			mode.InitialStatusBits.RTS = *o.rts
+		}
+	}
+
+	return mode
+}
+
+// logger returns the logger for transport events, which discards them if
+// logging is not enabled.
+func (o *ConnectOptions) logger() *slog.Logger {
+	switch {
+	case o.log == nil:
+		return slog.New(slog.DiscardHandler)
+	case o.log.Logger != nil:
+		return o.log.Logger
+	}
+	return slog.Default()
 }
 
 type ConnectOption func(*ConnectOptions)
 
-func OnRecv(fn func(code meshcore.NotificationCode, data []byte)) ConnectOption {
+// BaudRate sets the baud rate of the port, which defaults to 115200.
+func BaudRate(baud int) ConnectOption {
+	return func(opts *ConnectOptions) {
+		opts.baud = baud
+	}
+}
+
+// WithParity sets the parity of the port, which defaults to ParityNone.
+func WithParity(parity Parity) ConnectOption {
 	return func(opts *ConnectOptions) {
-		opts.onRecv = fn
+		opts.parity = parity
 	}
 }
 
-func OnSend(fn func(code meshcore.CommandCode, data []byte)) ConnectOption {
+// WithStopBits sets the stop bits of the port, which default to StopBitsOne.
+func WithStopBits(stopBits StopBits) ConnectOption {
 	return func(opts *ConnectOptions) {
-		opts.onSend = fn
+		opts.stopBits = stopBits
+	}
+}
+
+// DTR sets the state of the DTR line when the port is opened. It is
+// asserted by default, which resets some boards.
+func DTR(on bool) ConnectOption {
+	return func(opts *ConnectOptions) {
+		opts.dtr = &on
+	}
+}
+
+// RTS sets the state of the RTS line when the port is opened. It is
+// asserted by default.
+func RTS(on bool) ConnectOption {
+	return func(opts *ConnectOptions) {
+		opts.rts = &on
+	}
+}
+
+// ReadTimeout limits how long a read from the port may wait for data. Once
+// set, a frame that stops arriving part way through is discarded and
+// reported as a reboot rather than blocking until more bytes arrive.
+func ReadTimeout(d time.Duration) ConnectOption {
+	return func(opts *ConnectOptions) {
+		opts.readTimeout = d
+	}
+}
+
+// Exclusive locks the port so that other processes that also lock it, as
+// most serial tools do, cannot open it while it is connected.
+func Exclusive() ConnectOption {
+	return func(opts *ConnectOptions) {
+		opts.exclusive = true
+	}
+}
+
+// OnReboot calls fn when the device appears to have rebooted, so that state
+// it lost can be restored, e.g. by calling meshcore.TimeSync.Trigger. It is
+// called from the goroutine that reads the port and must not block.
+func OnReboot(fn func(*RebootEvent)) ConnectOption {
+	return func(opts *ConnectOptions) {
+		opts.onReboot = fn
+	}
+}
+
+// Middleware passes the connection's frames through mws. See
+// meshcore.WithMiddleware for how they are ordered.
+func Middleware(mws ...meshcore.Middleware) ConnectOption {
+	return func(opts *ConnectOptions) {
+		opts.middleware = append(opts.middleware, mws...)
+	}
+}
+
+// Logging logs the connection's commands and notifications, along with
+// transport events like connects, disconnects and errors.
+func Logging(opts *meshcore.LogOptions) ConnectOption {
+	return func(o *ConnectOptions) {
+		if opts == nil {
+			opts = &meshcore.LogOptions{}
+		}
+		o.log = opts
 	}
 }
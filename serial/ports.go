@@ -0,0 +1,28 @@
+//go:build !darwin || cgo
+
+package serial
+
+import (
+	"github.com/kellegous/poop"
+	"go.bug.st/serial/enumerator"
+)
+
+func listPorts() ([]*Port, error) {
+	details, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	ports := make([]*Port, 0, len(details))
+	for _, d := range details {
+		ports = append(ports, &Port{
+			Name:         d.Name,
+			IsUSB:        d.IsUSB,
+			VID:          d.VID,
+			PID:          d.PID,
+			SerialNumber: d.SerialNumber,
+			Product:      d.Product,
+		})
+	}
+	return ports, nil
+}
@@ -0,0 +1,12 @@
+//go:build darwin && !cgo
+
+package serial
+
+import (
+	"github.com/kellegous/poop"
+)
+
+// The enumerator uses IOKit through cgo on darwin.
+func listPorts() ([]*Port, error) {
+	return nil, poop.New("listing serial ports requires cgo on darwin")
+}
@@ -3,40 +3,55 @@ package serial
 import (
 	"bytes"
 	"encoding/binary"
+	"io"
+	"log/slog"
 	"sync/atomic"
 
 	"github.com/kellegous/meshcore"
 	"github.com/kellegous/poop"
-	"go.bug.st/serial"
 )
 
 type tx struct {
-	port           serial.Port
+	port           io.ReadWriteCloser
+	lock           io.Closer
 	isDisconnected atomic.Bool
+	err            atomic.Pointer[error]
 	*meshcore.NotificationCenter
-	opts *ConnectOptions
+	logger *slog.Logger
 }
 
 var _ meshcore.Transport = (*tx)(nil)
 
 func (t *tx) Write(p []byte) (int, error) {
+	if err := t.err.Load(); err != nil {
+		return 0, poop.ChainWith(*err, "serial port failed")
+	}
+
 	var buf bytes.Buffer
 	buf.WriteByte(outgoingFrameType)
 	binary.Write(&buf, binary.LittleEndian, uint16(len(p)))
 	buf.Write(p)
 
-	if nf := t.opts.onSend; nf != nil && len(p) > 0 {
-		nf(meshcore.CommandCode(p[0]), p[1:])
-	}
-
 	n, err := t.port.Write(buf.Bytes())
 	if err != nil {
+		t.logger.Error("serial write failed", slog.Any("error", err))
 		return 0, poop.Chain(err)
 	}
 	return n - 3, nil
 }
 
+// fail stops the transport after err was read from the port.
+func (t *tx) fail(err error) {
+	t.err.Store(&err)
+	t.Shutdown()
+}
+
 func (t *tx) Disconnect() error {
 	t.isDisconnected.Store(true)
-	return t.port.Close()
+	t.logger.Info("serial port closed")
+	err := t.port.Close()
+	if t.lock != nil {
+		t.lock.Close()
+	}
+	return err
 }
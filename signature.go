@@ -0,0 +1,136 @@
+package meshcore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+
+	"github.com/kellegous/poop"
+)
+
+const (
+	signaturePEMType = "MESHCORE SIGNATURE"
+
+	// signedDigestPrefix is prepended to the digest before it is signed so
+	// that a detached signature can never be mistaken for a signature over
+	// some other 32 byte message.
+	signedDigestPrefix = "meshcore-detached-signature-v1:"
+)
+
+// DetachedSignature is a signature over the SHA-256 digest of some content
+// made with a device's identity. It is stored separately from the content it
+// signs, which allows signing content that is larger than a device will sign
+// directly.
+//
+// The text form is a PEM block:
+//
+//	-----BEGIN MESHCORE SIGNATURE-----
+//	Digest: <hex sha-256 digest of the content>
+//	Public-Key: <hex public key of the signer>
+//
+//	<base64 ed25519 signature>
+//	-----END MESHCORE SIGNATURE-----
+type DetachedSignature struct {
+	PublicKey PublicKey
+	Digest    [sha256.Size]byte
+	Signature [64]byte
+}
+
+func digestOf(r io.Reader) ([sha256.Size]byte, error) {
+	var digest [sha256.Size]byte
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return digest, poop.Chain(err)
+	}
+	copy(digest[:], h.Sum(nil))
+	return digest, nil
+}
+
+func signedDigest(digest [sha256.Size]byte) []byte {
+	return append([]byte(signedDigestPrefix), digest[:]...)
+}
+
+// SignDetached creates a detached signature of the content read from r using
+// the device's identity.
+func (c *Conn) SignDetached(ctx context.Context, r io.Reader) (*DetachedSignature, error) {
+	digest, err := digestOf(r)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	info, err := c.GetSelfInfo(ctx)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	sig, err := c.Sign(ctx, signedDigest(digest))
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	s := &DetachedSignature{
+		PublicKey: info.PublicKey,
+		Digest:    digest,
+	}
+	copy(s.Signature[:], sig)
+	return s, nil
+}
+
+// Verify checks that the signature is valid for the content read from r.
+func (s *DetachedSignature) Verify(r io.Reader) error {
+	digest, err := digestOf(r)
+	if err != nil {
+		return poop.Chain(err)
+	}
+
+	if digest != s.Digest {
+		return poop.New("digest does not match content")
+	}
+
+	if !s.PublicKey.Verify(signedDigest(digest), s.Signature[:]) {
+		return poop.New("invalid signature")
+	}
+
+	return nil
+}
+
+func (s *DetachedSignature) MarshalText() ([]byte, error) {
+	return pem.EncodeToMemory(&pem.Block{
+		Type: signaturePEMType,
+		Headers: map[string]string{
+			"Public-Key": s.PublicKey.String(),
+			"Digest":     hex.EncodeToString(s.Digest[:]),
+		},
+		Bytes: s.Signature[:],
+	}), nil
+}
+
+func (s *DetachedSignature) UnmarshalText(text []byte) error {
+	block, _ := pem.Decode(text)
+	if block == nil || block.Type != signaturePEMType {
+		return poop.New("no signature block found")
+	}
+
+	key, err := ParsePublicKey(block.Headers["Public-Key"])
+	if err != nil {
+		return poop.Chain(err)
+	}
+
+	digest, err := hex.DecodeString(block.Headers["Digest"])
+	if err != nil {
+		return poop.Chain(err)
+	} else if len(digest) != len(s.Digest) {
+		return poop.Newf("digest must be %d bytes, got %d", len(s.Digest), len(digest))
+	}
+
+	if len(block.Bytes) != len(s.Signature) {
+		return poop.Newf("signature must be %d bytes, got %d", len(s.Signature), len(block.Bytes))
+	}
+
+	s.PublicKey = key
+	copy(s.Digest[:], digest)
+	copy(s.Signature[:], block.Bytes)
+	return nil
+}
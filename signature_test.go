@@ -0,0 +1,137 @@
+package meshcore
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestSignDetached(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := PublicKeyFromBytes(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte(strings.Repeat("config bundle ", 1000))
+
+	var sig *DetachedSignature
+	controller := DoCommand(func(conn *Conn) {
+		var err error
+		sig, err = conn.SignDetached(t.Context(), bytes.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if err := ValidateBytes(
+		controller.Recv(),
+		Command(CommandAppStart),
+		Byte(1),
+		Bytes(0, 0, 0, 0, 0, 0),
+		String("test"),
+	); err != nil {
+		t.Fatal(err)
+	}
+	controller.Notify(NotificationTypeSelfInfo, SelfInfoFrom(&SelfInfo{
+		PublicKey: key,
+		Name:      "signer",
+	}))
+
+	if err := ValidateBytes(controller.Recv(), Command(CommandSignStart)); err != nil {
+		t.Fatal(err)
+	}
+	controller.Notify(NotificationTypeSignStart, BytesFrom(
+		Byte(0),
+		Uint32(8192, binary.LittleEndian),
+	))
+
+	var data []byte
+	if err := ValidateBytes(
+		controller.Recv(),
+		Command(CommandSignData),
+		AnyBytesCapture(len(signedDigestPrefix)+32, &data),
+	); err != nil {
+		t.Fatal(err)
+	}
+	controller.Notify(NotificationTypeOk, nil)
+
+	if err := ValidateBytes(controller.Recv(), Command(CommandSignFinish)); err != nil {
+		t.Fatal(err)
+	}
+	controller.Notify(NotificationTypeSignature, ed25519.Sign(priv, data))
+
+	controller.Wait()
+
+	if err := sig.Verify(bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := sig.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed DetachedSignature
+	if err := parsed.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if parsed != *sig {
+		t.Fatalf("expected %s, got %s", describe(sig), describe(&parsed))
+	}
+
+	if err := parsed.Verify(bytes.NewReader(content[1:])); err == nil || err.Error() != "digest does not match content" {
+		t.Fatalf("expected error: digest does not match content, got %v", err)
+	}
+
+	parsed.Signature[0] ^= 0xff
+	if err := parsed.Verify(bytes.NewReader(content)); err == nil || err.Error() != "invalid signature" {
+		t.Fatalf("expected error: invalid signature, got %v", err)
+	}
+}
+
+func TestDetachedSignatureUnmarshalText(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Input         string
+		ExpectedError string
+	}{
+		{
+			Name:          "no block",
+			Input:         "not a signature",
+			ExpectedError: "no signature block found",
+		},
+		{
+			Name: "wrong type",
+			Input: "-----BEGIN PUBLIC KEY-----\n" +
+				"AAAA\n" +
+				"-----END PUBLIC KEY-----\n",
+			ExpectedError: "no signature block found",
+		},
+		{
+			Name: "short signature",
+			Input: "-----BEGIN MESHCORE SIGNATURE-----\n" +
+				"Digest: 0000000000000000000000000000000000000000000000000000000000000000\n" +
+				"Public-Key: 0000000000000000000000000000000000000000000000000000000000000000\n" +
+				"\n" +
+				"AAAA\n" +
+				"-----END MESHCORE SIGNATURE-----\n",
+			ExpectedError: "signature must be 64 bytes, got 3",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var sig DetachedSignature
+			if err := sig.UnmarshalText([]byte(test.Input)); err == nil || err.Error() != test.ExpectedError {
+				t.Fatalf("expected error %q, got %v", test.ExpectedError, err)
+			}
+		})
+	}
+}
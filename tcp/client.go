@@ -0,0 +1,60 @@
+// Package tcp connects to devices with WiFi, which serve the companion
+// protocol over TCP using the same framing as the serial transport.
+package tcp
+
+import (
+	"context"
+	"net"
+	"net/url"
+
+	"github.com/kellegous/meshcore"
+	meshcore_serial "github.com/kellegous/meshcore/serial"
+	"github.com/kellegous/poop"
+)
+
+// DefaultPort is the port used when an address has none.
+const DefaultPort = "5000"
+
+func init() {
+	meshcore.RegisterDialer("tcp", dial)
+}
+
+// Connect connects to the device at address, a host with an optional port.
+// The options are those of the serial transport, apart from the port mode
+// and Exclusive, which are ignored.
+func Connect(
+	ctx context.Context,
+	address string,
+	opts ...meshcore_serial.ConnectOption,
+) (*meshcore.Conn, error) {
+	address = withDefaultPort(address)
+
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+
+	conn, err := meshcore_serial.NewConnection(c, address, opts...)
+	if err != nil {
+		c.Close()
+		return nil, poop.Chain(err)
+	}
+	return conn, nil
+}
+
+// withDefaultPort adds DefaultPort to address if it has no port.
+func withDefaultPort(address string) string {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return net.JoinHostPort(address, DefaultPort)
+	}
+	return address
+}
+
+// dial connects to a URL like tcp://host:5000.
+func dial(ctx context.Context, addr string, query url.Values) (*meshcore.Conn, error) {
+	if err := meshcore.CheckDialQuery(query); err != nil {
+		return nil, poop.Chain(err)
+	}
+	return Connect(ctx, addr)
+}
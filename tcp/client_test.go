@@ -0,0 +1,92 @@
+package tcp
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/kellegous/meshcore"
+)
+
+func TestWithDefaultPort(t *testing.T) {
+	for address, expected := range map[string]string{
+		"meshcore.local":      "meshcore.local:5000",
+		"meshcore.local:4000": "meshcore.local:4000",
+		"10.0.0.2":            "10.0.0.2:5000",
+		"::1":                 "[::1]:5000",
+		"[::1]:4000":          "[::1]:4000",
+	} {
+		if got := withDefaultPort(address); got != expected {
+			t.Errorf("%s: expected %s, got %s", address, expected, got)
+		}
+	}
+}
+
+// serveBattery accepts one connection and answers each command with a
+// battery voltage, using the serial framing.
+func serveBattery(t *testing.T, ln net.Listener) {
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	for {
+		var hdr [3]byte
+		if _, err := io.ReadFull(conn, hdr[:]); err != nil {
+			return
+		}
+		if _, err := io.CopyN(io.Discard, conn, int64(binary.LittleEndian.Uint16(hdr[1:]))); err != nil {
+			return
+		}
+		conn.Write([]byte{'>', 3, 0, byte(meshcore.NotificationTypeBatteryVoltage), 0x93, 0x0f})
+	}
+}
+
+func TestConnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go serveBattery(t, ln)
+
+	conn, err := Connect(t.Context(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Disconnect()
+
+	v, err := conn.GetBatteryVoltage(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 3987 {
+		t.Fatalf("expected 3987, got %d", v)
+	}
+}
+
+func TestDial(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go serveBattery(t, ln)
+
+	if _, err := meshcore.Dial(t.Context(), "tcp://"+ln.Addr().String()+"?baud=9600"); err == nil || err.Error() != `unknown option: "baud"` {
+		t.Fatalf("expected an unknown option error, got %v", err)
+	}
+
+	conn, err := meshcore.Dial(t.Context(), "tcp://"+ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Disconnect()
+
+	if _, err := conn.GetBatteryVoltage(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+}
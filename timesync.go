@@ -0,0 +1,155 @@
+package meshcore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kellegous/poop"
+)
+
+// DriftSample is a single comparison of a device's clock with the host's.
+type DriftSample struct {
+	// At is the host time when the device's clock was read.
+	At time.Time
+	// DeviceTime is the time reported by the device.
+	DeviceTime time.Time
+	// Drift is how far the device's clock is ahead of the host's. It is
+	// negative if the device is behind.
+	Drift time.Duration
+	// Corrected is true if the device's clock was set to the host's.
+	Corrected bool
+}
+
+type TimeSyncOptions struct {
+	// Threshold is how far the device's clock may fall behind the host's
+	// before it is corrected. If zero, 5 seconds is used. Devices only report
+	// time to the second, so smaller thresholds are not useful.
+	Threshold time.Duration
+	// Interval is the time between checks made by Run. If zero, one hour is
+	// used.
+	Interval time.Duration
+	// HistorySize is the number of samples kept by History. If zero, 100 is
+	// used.
+	HistorySize int
+	// OnError receives the errors from checks made by Run. If nil, those
+	// errors are discarded.
+	OnError func(error)
+}
+
+// TimeSync keeps a device's clock in sync with the host's. Radios without a
+// real time clock boot with the wrong time, which makes the timestamps on
+// everything they send wrong.
+//
+// Devices refuse to move their clocks backwards, so a device whose clock is
+// ahead of the host's cannot be corrected; the drift is still recorded.
+type TimeSync struct {
+	conn    *Conn
+	opts    TimeSyncOptions
+	now     func() time.Time
+	trigger chan struct{}
+
+	mu      sync.Mutex
+	history []DriftSample
+}
+
+// NewTimeSync creates a TimeSync for the device on conn.
+func NewTimeSync(conn *Conn, opts *TimeSyncOptions) *TimeSync {
+	s := &TimeSync{
+		conn:    conn,
+		now:     time.Now,
+		trigger: make(chan struct{}, 1),
+	}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Threshold == 0 {
+		s.opts.Threshold = 5 * time.Second
+	}
+	if s.opts.Interval == 0 {
+		s.opts.Interval = time.Hour
+	}
+	if s.opts.HistorySize == 0 {
+		s.opts.HistorySize = 100
+	}
+	return s
+}
+
+// Check compares the device's clock with the host's and corrects it if it is
+// behind by more than the threshold. A clock that is ahead is recorded but
+// not corrected. The sample is recorded even if the correction fails.
+func (s *TimeSync) Check(ctx context.Context) (*DriftSample, error) {
+	before := s.now()
+	deviceTime, err := s.conn.GetDeviceTime(ctx)
+	if err != nil {
+		return nil, poop.Chain(err)
+	}
+	// The device read its clock somewhere during the round trip.
+	at := before.Add(s.now().Sub(before) / 2)
+
+	sample := &DriftSample{
+		At:         at,
+		DeviceTime: deviceTime,
+		Drift:      deviceTime.Sub(at.Truncate(time.Second)),
+	}
+
+	if sample.Drift < -s.opts.Threshold {
+		err = s.conn.SetDeviceTime(ctx, s.now())
+		sample.Corrected = err == nil
+	}
+
+	s.record(sample)
+
+	if err != nil {
+		return sample, poop.Chain(err)
+	}
+	return sample, nil
+}
+
+func (s *TimeSync) record(sample *DriftSample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history = append(s.history, *sample)
+	if n := len(s.history) - s.opts.HistorySize; n > 0 {
+		s.history = s.history[n:]
+	}
+}
+
+// History returns the recorded samples, oldest first.
+func (s *TimeSync) History() []DriftSample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]DriftSample(nil), s.history...)
+}
+
+// Trigger asks Run to check the clock now instead of waiting for the next
+// interval. Call it after the transport reconnects or the device reboots,
+// such as from the bluetooth OnStateChange or serial OnReboot options. It
+// does not block.
+func (s *TimeSync) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run checks the clock immediately, then after each interval and each
+// Trigger, until ctx is done.
+func (s *TimeSync) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Check(ctx); err != nil && ctx.Err() == nil && s.opts.OnError != nil {
+			s.opts.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return poop.Chain(ctx.Err())
+		case <-ticker.C:
+		case <-s.trigger:
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package meshcore
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeSyncCheck(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		Name       string
+		DeviceTime time.Time
+		SetError   ErrorCode
+		Corrected  bool
+		Error      string
+	}{
+		{
+			Name:       "within threshold",
+			DeviceTime: now.Add(-2 * time.Second),
+		},
+		{
+			Name:       "behind",
+			DeviceTime: time.Unix(0, 0),
+			Corrected:  true,
+		},
+		{
+			Name:       "ahead",
+			DeviceTime: now.Add(time.Minute),
+		},
+		{
+			Name:       "behind and rejected",
+			DeviceTime: now.Add(-time.Minute),
+			SetError:   ErrorCodeIllegalArgument,
+			Error:      "error: 6 (illegal argument)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var s *TimeSync
+			controller := DoCommand(func(conn *Conn) {
+				s = NewTimeSync(conn, nil)
+				s.now = func() time.Time { return now }
+
+				sample, err := s.Check(t.Context())
+				if test.Error == "" && err != nil {
+					t.Fatal(err)
+				} else if test.Error != "" && (err == nil || err.Error() != test.Error) {
+					t.Fatalf("expected error %q, got %v", test.Error, err)
+				}
+
+				expected := DriftSample{
+					At:         now,
+					DeviceTime: test.DeviceTime,
+					Drift:      test.DeviceTime.Sub(now),
+					Corrected:  test.Corrected,
+				}
+				if *sample != expected {
+					t.Fatalf("expected %s, got %s", describe(expected), describe(sample))
+				}
+			})
+
+			if err := ValidateBytes(
+				controller.Recv(),
+				Command(CommandGetDeviceTime),
+			); err != nil {
+				t.Fatal(err)
+			}
+			controller.Notify(NotificationTypeCurrTime, BytesFrom(Time(test.DeviceTime, binary.LittleEndian)))
+
+			if test.Corrected || test.SetError != 0 {
+				if err := ValidateBytes(
+					controller.Recv(),
+					Command(CommandSetDeviceTime),
+					Time(now, binary.LittleEndian),
+				); err != nil {
+					t.Fatal(err)
+				}
+				if test.SetError != 0 {
+					controller.Notify(NotificationTypeErr, BytesFrom(Byte(byte(test.SetError))))
+				} else {
+					controller.Notify(NotificationTypeOk, nil)
+				}
+			}
+
+			controller.Wait()
+
+			if history := s.History(); len(history) != 1 {
+				t.Fatalf("expected 1 sample, got %d", len(history))
+			}
+		})
+	}
+}
+
+func TestTimeSyncRun(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	var s *TimeSync
+	ready := make(chan struct{})
+	controller := DoCommand(func(conn *Conn) {
+		s = NewTimeSync(conn, &TimeSyncOptions{
+			Interval:    time.Hour,
+			HistorySize: 2,
+		})
+		s.now = func() time.Time { return now }
+		close(ready)
+
+		if err := s.Run(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	})
+	<-ready
+
+	for i := range 3 {
+		if err := ValidateBytes(
+			controller.Recv(),
+			Command(CommandGetDeviceTime),
+		); err != nil {
+			t.Fatal(err)
+		}
+		controller.Notify(NotificationTypeCurrTime, BytesFrom(
+			Time(now.Add(time.Duration(i)*time.Second), binary.LittleEndian)))
+		s.Trigger()
+	}
+
+	// Drain the check made for the last trigger.
+	controller.Recv()
+	cancel()
+	controller.Wait()
+
+	history := s.History()
+	if len(history) != 2 || history[0].Drift != time.Second || history[1].Drift != 2*time.Second {
+		t.Fatalf("unexpected history: %s", describe(history))
+	}
+}
@@ -205,6 +205,23 @@ func BinaryResponseFrom(
 	return BytesFrom(all...)
 }
 
+func SelfInfoFrom(info *SelfInfo) []byte {
+	return BytesFrom(
+		Byte(info.Type),
+		Byte(info.TxPower),
+		Byte(info.MaxTxPower),
+		Bytes(info.PublicKey.Bytes()...),
+		LatLon(info.AdvLat, info.AdvLon, binary.LittleEndian),
+		Bytes(0, 0, 0),
+		Byte(info.ManualAddContacts),
+		Uint32(uint32(info.RadioFreq*1000), binary.LittleEndian),
+		Uint32(uint32(info.RadioBw*1000), binary.LittleEndian),
+		Byte(info.RadioSf),
+		Byte(info.RadioCr),
+		String(info.Name),
+	)
+}
+
 func BinaryRequest(
 	recipient PublicKey,
 	patterns ...Pattern,